@@ -0,0 +1,60 @@
+// Command simulate runs a what-if simulation of a venue's queue against the database in DB_DSN
+// and prints the result as JSON. Nothing is written to the database.
+//
+//	simulate -venue 1 -date 2026-03-02
+//	simulate -venue 1 -scenario more-counters.json
+//
+// The scenario file holds the same JSON as POST /venues/:id/simulation.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"queue-system-backend/database"
+	"queue-system-backend/models"
+)
+
+func main() {
+	venueID := flag.Uint("venue", 0, "venue to simulate")
+	scenarioPath := flag.String("scenario", "", "JSON scenario file; without one the venue's day is replayed as is")
+	date := flag.String("date", "", "business day (YYYY-MM-DD) to simulate, overriding the scenario's")
+	seed := flag.Int64("seed", 0, "random seed, overriding the scenario's")
+	flag.Parse()
+
+	if *venueID == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var scenario models.SimulationScenario
+	if *scenarioPath != "" {
+		data, err := os.ReadFile(*scenarioPath)
+		if err != nil {
+			log.Fatalf("❌ Failed to read scenario: %v", err)
+		}
+		if err := json.Unmarshal(data, &scenario); err != nil {
+			log.Fatalf("❌ Invalid scenario: %v", err)
+		}
+	}
+	if *date != "" {
+		scenario.Date = *date
+	}
+	if *seed != 0 {
+		scenario.Seed = *seed
+	}
+
+	database.ConnectDB()
+
+	result, err := models.SimulateQueue(uint(*venueID), scenario)
+	if err != nil {
+		log.Fatalf("❌ Simulation failed: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatalf("❌ Failed to write result: %v", err)
+	}
+}
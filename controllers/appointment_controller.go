@@ -0,0 +1,411 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"queue-system-backend/models"
+	"queue-system-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slotTemplateInput is the body of slot template create and update requests
+type slotTemplateInput struct {
+	Weekdays    string `json:"weekdays"`
+	StartTime   string `json:"start_time"`
+	EndTime     string `json:"end_time"`
+	SlotMinutes int    `json:"slot_minutes" binding:"required"`
+	Capacity    int    `json:"capacity"` // Defaults to 1
+	Active      *bool  `json:"active"`   // Defaults to true
+}
+
+// apply copies the input onto a slot template
+func (input *slotTemplateInput) apply(template *models.AppointmentSlotTemplate) {
+	template.Weekdays = input.Weekdays
+	template.StartTime = input.StartTime
+	template.EndTime = input.EndTime
+	template.SlotMinutes = input.SlotMinutes
+	template.Capacity = input.Capacity
+	if template.Capacity == 0 {
+		template.Capacity = 1
+	}
+	template.Active = input.Active == nil || *input.Active
+}
+
+// checkInInput is the body of appointment check-in requests: the booking code or the scanned QR payload
+type checkInInput struct {
+	Code string `json:"code" binding:"required_without=QR"`
+	QR   string `json:"qr"`
+}
+
+// bookingCode returns the booking code to check in
+func (input *checkInInput) bookingCode() string {
+	if input.Code != "" {
+		return input.Code
+	}
+	return models.BookingCodeFromQR(input.QR)
+}
+
+// ListAppointmentSlots lists the bookable slots of a service on a date
+func ListAppointmentSlots(c *gin.Context) {
+	serviceID, err := strconv.Atoi(c.Query("service_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+	date := c.Query("date")
+	if date == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date is required"})
+		return
+	}
+
+	slots, err := models.GetAppointmentSlots(uint(serviceID), date)
+	if err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": "Failed to fetch slots", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+// BookAppointmentHandler books an appointment slot for a customer
+func BookAppointmentHandler(c *gin.Context) {
+	var input struct {
+		ServiceID     uint      `json:"service_id" binding:"required"`
+		SlotStart     time.Time `json:"slot_start" binding:"required"`
+		CustomerName  string    `json:"customer_name" binding:"required"`
+		CustomerEmail string    `json:"customer_email" binding:"omitempty,email"`
+		CustomerPhone string    `json:"customer_phone"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	appointment := models.Appointment{
+		ServiceID:     input.ServiceID,
+		SlotStart:     input.SlotStart,
+		CustomerName:  input.CustomerName,
+		CustomerEmail: input.CustomerEmail,
+		CustomerPhone: input.CustomerPhone,
+	}
+	if err := models.BookAppointment(&appointment); err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": "Failed to book appointment", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"appointment": appointment, "qr": appointment.QRPayload()})
+}
+
+// GetAppointmentByCodeHandler retrieves an appointment by its booking code
+func GetAppointmentByCodeHandler(c *gin.Context) {
+	appointment, err := models.GetAppointmentByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"appointment": appointment, "qr": appointment.QRPayload()})
+}
+
+// CancelAppointmentByCodeHandler lets a customer cancel their appointment
+func CancelAppointmentByCodeHandler(c *gin.Context) {
+	appointment, err := models.GetAppointmentByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.CancelAppointment(appointment, true); err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": "Failed to cancel appointment", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+// RescheduleAppointmentHandler lets a customer move their appointment to another slot
+func RescheduleAppointmentHandler(c *gin.Context) {
+	var input struct {
+		SlotStart time.Time `json:"slot_start" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	appointment, err := models.RescheduleAppointment(c.Param("code"), input.SlotStart)
+	if err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": "Failed to reschedule appointment", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"appointment": appointment, "qr": appointment.QRPayload()})
+}
+
+// ListVenueAppointments lists a venue's appointments on a date, optionally for one service
+func ListVenueAppointments(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	date := c.Query("date")
+	if date == "" {
+		date = time.Now().In(venue.Location()).Format("2006-01-02")
+	}
+	var serviceID uint
+	if value := c.Query("service_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+			return
+		}
+		serviceID = uint(id)
+	}
+
+	appointments, err := models.GetAppointments(venue, serviceID, date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to fetch appointments", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, appointments)
+}
+
+// CheckInAppointmentHandler checks in an appointment at the counter and issues its ticket
+func CheckInAppointmentHandler(c *gin.Context) {
+	var input checkInInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	appointment, err := models.GetAppointmentByCode(input.bookingCode())
+	if err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !canAccessVenue(c, appointment.UserID) {
+		return
+	}
+
+	checkInAppointment(c, appointment.BookingCode, nil)
+}
+
+// KioskCheckInHandler checks in an appointment at a kiosk of its venue and issues its ticket
+func KioskCheckInHandler(c *gin.Context) {
+	device := c.MustGet("kiosk").(*models.KioskDevice)
+
+	var input checkInInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	checkInAppointment(c, input.bookingCode(), device)
+}
+
+// checkInAppointment checks in the appointment with the booking code and responds with its ticket
+func checkInAppointment(c *gin.Context, code string, kiosk *models.KioskDevice) {
+	appointment, ticket, err := models.CheckInAppointment(code, kiosk)
+	if err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": "Failed to check in appointment", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"appointment": appointment, "ticket": ticket})
+}
+
+// CancelAppointmentHandler cancels an appointment on the customer's behalf
+func CancelAppointmentHandler(c *gin.Context) {
+	appointment, ok := accessibleAppointment(c)
+	if !ok {
+		return
+	}
+
+	if err := models.CancelAppointment(appointment, false); err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": "Failed to cancel appointment", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+// MarkAppointmentNoShowHandler records that the customer of an appointment didn't come
+func MarkAppointmentNoShowHandler(c *gin.Context) {
+	appointment, ok := accessibleAppointment(c)
+	if !ok {
+		return
+	}
+
+	if err := models.MarkAppointmentNoShow(appointment); err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": "Failed to mark appointment as no-show", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+// ListSlotTemplates retrieves the slot templates of a service
+func ListSlotTemplates(c *gin.Context) {
+	service, ok := ownedService(c)
+	if !ok {
+		return
+	}
+
+	templates, err := models.GetAppointmentSlotTemplates(service.ServiceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch slot templates", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// CreateSlotTemplate adds a slot template to a service
+func CreateSlotTemplate(c *gin.Context) {
+	service, ok := ownedService(c)
+	if !ok {
+		return
+	}
+	if service.VenueID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Service does not belong to a venue"})
+		return
+	}
+
+	var input slotTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	template := models.AppointmentSlotTemplate{ServiceID: service.ServiceID, VenueID: *service.VenueID}
+	input.apply(&template)
+
+	if err := models.CreateAppointmentSlotTemplate(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create slot template", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// UpdateSlotTemplate updates a slot template of a service
+func UpdateSlotTemplate(c *gin.Context) {
+	service, ok := ownedService(c)
+	if !ok {
+		return
+	}
+
+	templateID, err := strconv.Atoi(c.Param("template_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slot template ID"})
+		return
+	}
+
+	template, err := models.GetAppointmentSlotTemplateByID(service.ServiceID, uint(templateID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input slotTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	input.apply(template)
+
+	if err := models.UpdateAppointmentSlotTemplate(template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update slot template", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteSlotTemplate deletes a slot template of a service
+func DeleteSlotTemplate(c *gin.Context) {
+	service, ok := ownedService(c)
+	if !ok {
+		return
+	}
+
+	templateID, err := strconv.Atoi(c.Param("template_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slot template ID"})
+		return
+	}
+
+	if err := models.DeleteAppointmentSlotTemplate(service.ServiceID, uint(templateID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete slot template", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Slot template deleted successfully"})
+}
+
+// accessibleAppointment loads the appointment from the :id parameter and checks the user may manage its venue
+func accessibleAppointment(c *gin.Context) (*models.Appointment, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return nil, false
+	}
+
+	appointment, err := models.GetAppointmentByID(uint(id))
+	if err != nil {
+		c.JSON(appointmentErrorCode(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if !canAccessVenue(c, appointment.UserID) {
+		return nil, false
+	}
+	return appointment, true
+}
+
+// ownedService loads the service from the :id parameter and checks it belongs to the user
+func ownedService(c *gin.Context) (*models.Service, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return nil, false
+	}
+
+	service, err := models.GetServiceByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return nil, false
+	}
+
+	userClaims, ok := c.MustGet("claims").(*utils.Claims)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid token claims"})
+		return nil, false
+	}
+
+	if service.UserID == nil || *service.UserID != userClaims.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+	return service, true
+}
+
+// appointmentErrorCode maps appointment errors to HTTP status codes
+func appointmentErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrSlotUnavailable), errors.Is(err, models.ErrSlotFull), errors.Is(err, models.ErrAppointmentNotBooked):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrCheckInWindow), isIssuanceClosed(err), err.Error() == "appointment is at another venue":
+		return http.StatusForbidden
+	case err.Error() == "appointment not found", err.Error() == "service not found", err.Error() == "venue not found":
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -175,3 +175,60 @@ func GetCountersByVenue(c *gin.Context) {
 
 	c.JSON(http.StatusOK, counters)
 }
+
+// GetCounterServices retrieves the services a counter is skilled for
+func GetCounterServices(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	userID := c.GetUint("user_id")
+	isAdmin := c.GetString("role") == "admin"
+
+	counter, err := models.GetCounterByID(uint(id), userID, isAdmin)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Counter not found"})
+		return
+	}
+	if !isAdmin && counter.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	skills, err := models.GetCounterServices(counter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch counter services", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, skills)
+}
+
+// SetCounterServices replaces the services a counter is skilled for and their weights
+func SetCounterServices(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	userID := c.GetUint("user_id")
+	isAdmin := c.GetString("role") == "admin"
+
+	counter, err := models.GetCounterByID(uint(id), userID, isAdmin)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Counter not found"})
+		return
+	}
+	if !isAdmin && counter.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var input struct {
+		Services []models.CounterService `json:"services" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	if err := models.SetCounterServices(counter, input.Services); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update counter services", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"counter": counter, "services": input.Services})
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"queue-system-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenCounterSession opens the counter mapped to the operator
+func OpenCounterSession(c *gin.Context) {
+	session, err := models.OpenCounterSession(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(counterSessionErrorCode(err), gin.H{"error": "Failed to open counter", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// PauseCounterSession pauses the operator's counter with a reason code
+func PauseCounterSession(c *gin.Context) {
+	var input struct {
+		Reason string `json:"reason" binding:"required"` // break, prayer, system_issue or other
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	session, err := models.PauseCounterSession(c.GetUint("user_id"), input.Reason, input.Note)
+	if err != nil {
+		c.JSON(counterSessionErrorCode(err), gin.H{"error": "Failed to pause counter", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// ResumeCounterSession resumes the operator's paused counter
+func ResumeCounterSession(c *gin.Context) {
+	session, err := models.ResumeCounterSession(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(counterSessionErrorCode(err), gin.H{"error": "Failed to resume counter", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// CloseCounterSession closes the operator's counter
+func CloseCounterSession(c *gin.Context) {
+	session, err := models.CloseCounterSession(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(counterSessionErrorCode(err), gin.H{"error": "Failed to close counter", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// GetCurrentCounterSession retrieves the active session of the operator's counter
+func GetCurrentCounterSession(c *gin.Context) {
+	counterID, err := models.GetCounterIDByUserID(int(c.GetUint("user_id")))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No counter is mapped to this user"})
+		return
+	}
+
+	session, err := models.GetActiveCounterSession(uint(counterID))
+	if err != nil {
+		c.JSON(counterSessionErrorCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// GetCounterSessionLog retrieves a counter's sessions and pauses, by default for today
+func GetCounterSessionLog(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	userID := c.GetUint("user_id")
+	isAdmin := c.GetString("role") == "admin"
+
+	counter, err := models.GetCounterByID(uint(id), userID, isAdmin)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Counter not found"})
+		return
+	}
+	if !isAdmin && counter.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	location := time.Local
+	if counter.VenueID != nil {
+		if venue, err := models.GetVenueByID(*counter.VenueID); err == nil {
+			location = venue.Location()
+		}
+	}
+	from, to, err := parsePeriod(c.Query("from"), c.Query("to"), location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessions, err := models.GetCounterSessions(counter.CounterID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch counter sessions", "details": err.Error()})
+		return
+	}
+
+	entries := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		pauses, err := models.GetCounterSessionPauses(session.SessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch counter pauses", "details": err.Error()})
+			return
+		}
+		entries = append(entries, gin.H{"session": session, "pauses": pauses})
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// parsePeriod parses optional RFC 3339 bounds, defaulting to the start of today in the given time zone until now
+func parsePeriod(fromValue string, toValue string, location *time.Location) (time.Time, time.Time, error) {
+	now := time.Now().In(location)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	to := now
+
+	var err error
+	if fromValue != "" {
+		if from, err = time.Parse(time.RFC3339, fromValue); err != nil {
+			return from, to, errors.New("invalid from, expected RFC 3339")
+		}
+	}
+	if toValue != "" {
+		if to, err = time.Parse(time.RFC3339, toValue); err != nil {
+			return from, to, errors.New("invalid to, expected RFC 3339")
+		}
+	}
+	if !to.After(from) {
+		return from, to, errors.New("to must be after from")
+	}
+	return from, to, nil
+}
+
+// counterSessionErrorCode maps counter session errors to HTTP status codes
+func counterSessionErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrSessionAlreadyOpen), errors.Is(err, models.ErrNoActiveSession):
+		return http.StatusConflict
+	case err.Error() == "counter is already paused", err.Error() == "counter is not paused":
+		return http.StatusConflict
+	case err.Error() == "invalid pause reason":
+		return http.StatusBadRequest
+	case err.Error() == "no counter is mapped to this user":
+		return http.StatusForbidden
+	case err.Error() == "counter not found":
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"queue-system-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListKioskDevices retrieves the kiosks owned by the user
+func ListKioskDevices(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	devices, err := models.GetKioskDevicesByUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch kiosks", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// CreateKioskDevice registers a kiosk for one of the user's venues and returns its pairing code
+func CreateKioskDevice(c *gin.Context) {
+	var input struct {
+		VenueID          uint   `json:"venue_id" binding:"required"`
+		DeviceName       string `json:"device_name" binding:"required"`
+		DailyTicketLimit int    `json:"daily_ticket_limit"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+
+	// Validate the venue
+	venue, err := models.GetVenueByID(input.VenueID)
+	if err != nil || venue.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid venue or access denied"})
+		return
+	}
+
+	device := models.KioskDevice{
+		VenueID:          input.VenueID,
+		UserID:           userID,
+		DeviceName:       input.DeviceName,
+		DailyTicketLimit: input.DailyTicketLimit,
+	}
+	if err := models.CreateKioskDevice(&device); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create kiosk", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, device)
+}
+
+// GetKioskDevice retrieves a specific kiosk by ID
+func GetKioskDevice(c *gin.Context) {
+	device, ok := ownedKioskDevice(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// UpdateKioskDevice updates a kiosk's name, limit and active flag
+func UpdateKioskDevice(c *gin.Context) {
+	device, ok := ownedKioskDevice(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		DeviceName       string `json:"device_name" binding:"required"`
+		DailyTicketLimit int    `json:"daily_ticket_limit"`
+		IsActive         *bool  `json:"is_active"` // Unchanged when omitted
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	device.DeviceName = input.DeviceName
+	device.DailyTicketLimit = input.DailyTicketLimit
+	if input.IsActive != nil {
+		device.IsActive = *input.IsActive
+	}
+
+	if err := models.UpdateKioskDevice(device); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update kiosk", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// DeleteKioskDevice deletes a kiosk
+func DeleteKioskDevice(c *gin.Context) {
+	device, ok := ownedKioskDevice(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteKioskDevice(device.KioskID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete kiosk"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Kiosk deleted successfully"})
+}
+
+// ResetKioskPairing revokes a kiosk's API key and returns a new pairing code
+func ResetKioskPairing(c *gin.Context) {
+	device, ok := ownedKioskDevice(c)
+	if !ok {
+		return
+	}
+
+	if err := models.ResetKioskPairing(device); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset pairing code", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// PairKioskDevice exchanges a pairing code for the kiosk's API key
+func PairKioskDevice(c *gin.Context) {
+	var input struct {
+		PairingCode string `json:"pairing_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	device, apiKey, err := models.PairKioskDevice(input.PairingCode)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"kiosk_id":    device.KioskID,
+		"venue_id":    device.VenueID,
+		"device_name": device.DeviceName,
+		"api_key":     apiKey,
+	})
+}
+
+// ListKioskServices retrieves the services a kiosk can issue tickets for
+func ListKioskServices(c *gin.Context) {
+	device := c.MustGet("kiosk").(*models.KioskDevice)
+
+	services, err := models.GetServicesByVenue(device.VenueID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
+		return
+	}
+
+	c.JSON(http.StatusOK, services)
+}
+
+// CreateKioskTicketHandler issues a ticket from a kiosk without an owner JWT
+func CreateKioskTicketHandler(c *gin.Context) {
+	device := c.MustGet("kiosk").(*models.KioskDevice)
+
+	var input struct {
+		ServiceID     uint   `json:"service_id" binding:"required_without=WorkflowID"`
+		WorkflowID    *uint  `json:"workflow_id"` // Starts the ticket at the workflow's first step instead of service_id
+		CustomerName  string `json:"customer_name"`
+		CustomerEmail string `json:"customer_email" binding:"omitempty,email"`
+		CustomerPhone string `json:"customer_phone"`
+		Priority      string `json:"priority"` // regular (default) or elderly_disabled
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if input.Priority != "" && !models.IsKioskPriority(input.Priority) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority", "details": models.ErrKioskPriority.Error()})
+		return
+	}
+
+	ticket := models.QueueTicket{
+		ServiceID:     &input.ServiceID,
+		WorkflowID:    input.WorkflowID,
+		CustomerName:  input.CustomerName,
+		CustomerEmail: input.CustomerEmail,
+		CustomerPhone: input.CustomerPhone,
+		Priority:      input.Priority,
+	}
+
+	if err := models.IssueKioskTicket(device, &ticket); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrKioskLimitReached) {
+			status = http.StatusTooManyRequests
+		} else if errors.Is(err, models.ErrKioskPriority) {
+			status = http.StatusBadRequest
+		} else if isIssuanceClosed(err) || err.Error() == "service is not available at this kiosk" || err.Error() == "service not found" || isWorkflowUnavailable(err) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": "Failed to create ticket", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ticket)
+}
+
+// ownedKioskDevice loads the kiosk from the :id parameter and checks it belongs to the user
+func ownedKioskDevice(c *gin.Context) (*models.KioskDevice, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid kiosk ID"})
+		return nil, false
+	}
+
+	device, err := models.GetKioskDeviceByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kiosk not found"})
+		return nil, false
+	}
+
+	if device.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+
+	return device, true
+}
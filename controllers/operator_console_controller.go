@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"queue-system-backend/events"
+	"queue-system-backend/models"
+	"queue-system-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	consolePingInterval = 30 * time.Second
+	consoleReadTimeout  = 2 * consolePingInterval
+	consoleWriteTimeout = 10 * time.Second
+)
+
+var consoleUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// The JWT travels in the query string, so browsers may only connect from the frontend's origins.
+	// Clients that aren't browsers send no Origin.
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || utils.IsAllowedOrigin(origin)
+	},
+}
+
+// consoleCommand is a message sent by the operator console
+type consoleCommand struct {
+	Action   string `json:"action"` // call_next, call_batch, recall, skip, rejoin, complete, transfer, open, pause, resume or close
+	TicketID uint   `json:"ticket_id"`
+	Reason   string `json:"reason"` // Also the reason code of a pause
+	Note     string `json:"note"`
+	Count    int    `json:"count"` // Tickets called by call_batch
+
+	// Transfer target, see models.TransferTarget
+	ToServiceID  uint   `json:"to_service_id"`
+	ToCounterID  uint   `json:"to_counter_id"`
+	ToOperatorID uint   `json:"to_operator_id"`
+	Position     string `json:"position"`
+}
+
+// consoleMessage is a message sent to the operator console
+type consoleMessage struct {
+	Type   string      `json:"type"` // event, queue_state or result
+	Action string      `json:"action,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// consoleQueueState is the live state of the queues served by the operator's counter
+type consoleQueueState struct {
+	CounterID    uint                  `json:"counter_id"`
+	ServiceID    uint                  `json:"service_id"` // The counter's primary service
+	VenueID      uint                  `json:"venue_id"`
+	WaitingCount int64                 `json:"waiting_count"` // Total over all services
+	Services     []consoleServiceState `json:"services"`
+}
+
+// consoleServiceState is the waiting count of one service the counter is skilled for
+type consoleServiceState struct {
+	ServiceID    uint  `json:"service_id"`
+	Weight       int   `json:"weight"`
+	WaitingCount int64 `json:"waiting_count"`
+}
+
+// operatorConsole is one operator's WebSocket session bound to their mapped counter
+type operatorConsole struct {
+	conn       *websocket.Conn
+	client     *events.Client
+	out        chan consoleMessage
+	operatorID uint
+	counter    *models.Counter
+	skills     []models.CounterService
+	venueID    uint
+}
+
+// OperatorConsole upgrades to a WebSocket that streams live queue state for the operator's
+// counter and accepts queue commands. The JWT is read from the `token` query parameter
+// because browsers cannot set headers on WebSocket requests.
+func OperatorConsole(c *gin.Context) {
+	tokenString := c.Query("token")
+	if tokenString == "" {
+		tokenString = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	if tokenString == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token is required"})
+		return
+	}
+
+	claims, err := utils.ParseToken(tokenString)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	counterID, err := models.GetCounterIDByUserID(int(claims.UserID))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No counter is mapped to this user"})
+		return
+	}
+
+	counter, err := models.GetCounterByID(uint(counterID), claims.UserID, false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if counter.VenueID == nil || counter.ServiceID == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Counter has no venue or service assigned"})
+		return
+	}
+
+	skills, err := models.GetCounterServices(counter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch counter services", "details": err.Error()})
+		return
+	}
+
+	conn, err := consoleUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("🔴 WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	console := &operatorConsole{
+		conn:       conn,
+		client:     events.DefaultHub.Join(*counter.VenueID),
+		out:        make(chan consoleMessage, 16),
+		operatorID: claims.UserID,
+		counter:    counter,
+		skills:     skills,
+		venueID:    *counter.VenueID,
+	}
+
+	go console.writePump()
+	console.sendQueueState()
+	console.readPump()
+}
+
+// readPump executes commands until the connection closes
+func (oc *operatorConsole) readPump() {
+	defer func() {
+		events.DefaultHub.Leave(oc.client)
+		oc.conn.Close()
+	}()
+
+	oc.conn.SetReadDeadline(time.Now().Add(consoleReadTimeout))
+	oc.conn.SetPongHandler(func(string) error {
+		return oc.conn.SetReadDeadline(time.Now().Add(consoleReadTimeout))
+	})
+
+	for {
+		var command consoleCommand
+		if err := oc.conn.ReadJSON(&command); err != nil {
+			return
+		}
+		oc.execute(command)
+	}
+}
+
+// writePump is the only writer of the connection: it relays hub events, command results and pings
+func (oc *operatorConsole) writePump() {
+	ping := time.NewTicker(consolePingInterval)
+	defer func() {
+		ping.Stop()
+		oc.conn.Close()
+	}()
+
+	for {
+		select {
+		case event, ok := <-oc.client.Send:
+			if !ok {
+				return
+			}
+			if err := oc.write(consoleMessage{Type: "event", Data: event}); err != nil {
+				return
+			}
+			if event.ServiceID == 0 || oc.servesService(event.ServiceID) {
+				oc.sendQueueState()
+			}
+		case message := <-oc.out:
+			if err := oc.write(message); err != nil {
+				return
+			}
+		case <-ping.C:
+			oc.conn.SetWriteDeadline(time.Now().Add(consoleWriteTimeout))
+			if err := oc.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (oc *operatorConsole) write(message consoleMessage) error {
+	oc.conn.SetWriteDeadline(time.Now().Add(consoleWriteTimeout))
+	return oc.conn.WriteJSON(message)
+}
+
+// reply queues a message for the write pump without blocking on a stalled connection
+func (oc *operatorConsole) reply(message consoleMessage) {
+	select {
+	case oc.out <- message:
+	default:
+	}
+}
+
+// servesService reports whether the counter is skilled for the service
+func (oc *operatorConsole) servesService(serviceID uint) bool {
+	for _, skill := range oc.skills {
+		if skill.ServiceID == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTicket makes sure a ticket is in the counter's venue and of a service the counter is skilled for
+func (oc *operatorConsole) checkTicket(ticketID uint) error {
+	ticket, err := models.GetQueueTicketByID(ticketID, 0, true)
+	if err != nil {
+		return err
+	}
+	if ticket.VenueID == nil || *ticket.VenueID != oc.venueID || ticket.ServiceID == nil || !oc.servesService(*ticket.ServiceID) {
+		return errors.New("ticket is not in this counter's queues")
+	}
+	return nil
+}
+
+// sendQueueState sends the waiting counts of the counter's services
+func (oc *operatorConsole) sendQueueState() {
+	state := consoleQueueState{
+		CounterID: oc.counter.CounterID,
+		ServiceID: *oc.counter.ServiceID,
+		VenueID:   oc.venueID,
+	}
+
+	for _, skill := range oc.skills {
+		count, err := models.CountWaitingTickets(oc.venueID, skill.ServiceID)
+		if err != nil {
+			oc.reply(consoleMessage{Type: "queue_state", Error: err.Error()})
+			return
+		}
+		state.WaitingCount += count
+		state.Services = append(state.Services, consoleServiceState{
+			ServiceID:    skill.ServiceID,
+			Weight:       skill.Weight,
+			WaitingCount: count,
+		})
+	}
+
+	oc.reply(consoleMessage{Type: "queue_state", Data: state})
+}
+
+// execute runs a console command against the operator's counter and replies with the result
+func (oc *operatorConsole) execute(command consoleCommand) {
+	operatorID := oc.operatorID
+	counterID := oc.counter.CounterID
+
+	var result interface{}
+	var err error
+
+	switch command.Action {
+	case "recall", "rejoin", "skip", "complete", "transfer":
+		if err := oc.checkTicket(command.TicketID); err != nil {
+			oc.reply(consoleMessage{Type: "result", Action: command.Action, Error: err.Error()})
+			return
+		}
+	}
+
+	switch command.Action {
+	case "call_next":
+		result, _, err = models.CallNextTicket(counterID, &operatorID)
+	case "call_batch":
+		result, _, err = models.CallNextTickets(counterID, &operatorID, command.Count)
+	case "recall":
+		result, err = models.RecallQueueTicket(command.TicketID, &operatorID, &counterID, command.Reason)
+	case "rejoin":
+		result, err = models.RejoinQueueTicket(command.TicketID, &operatorID, &counterID)
+	case "skip":
+		err = models.UpdateQueueTicketStatus(command.TicketID, models.TicketStatusSkipped, &operatorID, &counterID, command.Reason)
+	case "complete":
+		result, err = models.CompleteQueueTicket(command.TicketID, &operatorID, &counterID)
+	case "transfer":
+		target := models.TransferTarget{
+			ServiceID:  command.ToServiceID,
+			CounterID:  command.ToCounterID,
+			OperatorID: command.ToOperatorID,
+			Position:   command.Position,
+			Reason:     command.Reason,
+		}
+		result, err = models.TransferQueueTicket(command.TicketID, target, &operatorID, &counterID)
+	case "open":
+		result, err = models.OpenCounterSession(operatorID)
+	case "pause":
+		result, err = models.PauseCounterSession(operatorID, command.Reason, command.Note)
+	case "resume":
+		result, err = models.ResumeCounterSession(operatorID)
+	case "close":
+		result, err = models.CloseCounterSession(operatorID)
+	default:
+		err = errors.New("unknown action")
+	}
+
+	message := consoleMessage{Type: "result", Action: command.Action, Data: result}
+	if err != nil {
+		message.Error = err.Error()
+	}
+	oc.reply(message)
+}
@@ -1,11 +1,16 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"queue-system-backend/events"
 	"queue-system-backend/models"
 	"strconv"
+	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
@@ -69,7 +74,7 @@ func UpdateQueueDisplay(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Display updated successfully"})
 }
 
-// AssignNextTicket assigns the next ticket to `CurrentTicket`
+// AssignNextTicket calls the oldest waiting ticket to the counter and updates its display
 func AssignNextTicket(c *gin.Context) {
 	counterID, err := strconv.Atoi(c.Param("counter_id"))
 	if err != nil {
@@ -77,18 +82,58 @@ func AssignNextTicket(c *gin.Context) {
 		return
 	}
 
-	display, err := models.GetQueueDisplayByCounterID(uint(counterID))
+	operatorID := c.GetUint("user_id")
+
+	ticket, display, err := models.CallNextTicket(uint(counterID), &operatorID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Queue display not found"})
+		switch {
+		case errors.Is(err, models.ErrNoWaitingTickets), err.Error() == "counter not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrCounterNotOpen):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	if err := display.AutoAssignNextTicket(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, gin.H{"message": "Next ticket assigned", "data": display, "ticket": ticket})
+}
+
+// AssignNextTickets calls several waiting tickets to the counter at once and shows them together on its display
+func AssignNextTickets(c *gin.Context) {
+	counterID, err := strconv.Atoi(c.Param("counter_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid counter ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Next ticket assigned", "data": display})
+	var input struct {
+		Count int `json:"count" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	operatorID := c.GetUint("user_id")
+
+	tickets, display, err := models.CallNextTickets(uint(counterID), &operatorID, input.Count)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoWaitingTickets), err.Error() == "counter not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrCounterNotOpen):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrBatchTooLarge):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Next tickets assigned", "data": display, "tickets": tickets})
 }
 
 // GetAllQueueDisplays retrieves all QueueDisplays with optional filters
@@ -194,11 +239,87 @@ func GetCurrentTicket(c *gin.Context) {
 
 	// Return the result
 	c.JSON(http.StatusOK, gin.H{
-		"current_ticket": queueDisplay.CurrentTicket,
-		"counter_id":     queueDisplay.CounterID,
-		"status":         "success",
-		"service_id":     queueDisplay.ServiceID,
-		"venue_id":       queueDisplay.VenueID,
+		"current_ticket":  queueDisplay.CurrentTicket,
+		"current_tickets": queueDisplay.CurrentTickets,
+		"counter_id":      queueDisplay.CounterID,
+		"status":          "success",
+		"service_id":      queueDisplay.ServiceID,
+		"venue_id":        queueDisplay.VenueID,
+	})
+}
+
+// displayStreamKeepAlive is how often an idle display stream sends a ping
+const displayStreamKeepAlive = 15 * time.Second
+
+// StreamQueueDisplay pushes a venue's queue events to display screens as Server-Sent Events.
+// Clients reconnecting with Last-Event-ID receive the events they missed.
+func StreamQueueDisplay(c *gin.Context) {
+	// The stream is public, so it is limited to one venue at a time
+	venueID := parseUint(c.Query("venue_id"))
+	if venueID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "venue_id is required"})
+		return
+	}
+	if _, err := models.GetVenueByID(venueID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	filter := events.Filter{
+		VenueID:   venueID,
+		ServiceID: parseUint(c.Query("service_id")),
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	lastID, _ := strconv.ParseUint(lastEventID, 10, 64)
+
+	subscription, missed := events.Subscribe(filter, lastID)
+	defer subscription.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	for _, event := range missed {
+		renderDisplayEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(displayStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-subscription.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects with Last-Event-ID
+				return false
+			}
+			renderDisplayEvent(c, event)
+			return true
+		case <-keepAlive.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// renderDisplayEvent writes a queue event in SSE format with its ID for reconnects.
+// Ticket IDs are left out: screens show queue numbers and the stream is public.
+func renderDisplayEvent(c *gin.Context, event events.Event) {
+	if ticket, ok := event.Data.(models.TicketEventData); ok {
+		ticket.TicketID = 0
+		ticket.CallBatchID = nil
+		event.Data = ticket
+	}
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: event.Type,
+		Data:  event,
 	})
 }
 
@@ -2,12 +2,9 @@
 package controllers
 
 import (
-	"crypto/sha256"
-	"encoding/base64"
+	"errors"
 	"net/http"
 	"strconv"
-	"strings"
-	"time"
 
 	"queue-system-backend/models"
 
@@ -39,82 +36,56 @@ func CreateQueueTicketHandler(c *gin.Context) {
 	// Parse POST input
 	var input struct {
 		VenueID       uint   `json:"venue_id" binding:"required"`
-		ServiceID     uint   `json:"service_id" binding:"required"`
+		ServiceID     uint   `json:"service_id" binding:"required_without=WorkflowID"`
+		WorkflowID    *uint  `json:"workflow_id"` // Starts the ticket at the workflow's first step instead of service_id
 		CustomerName  string `json:"customer_name" binding:"required"`
 		CustomerEmail string `json:"customer_email" binding:"required,email"`
 		CustomerPhone string `json:"customer_phone" binding:"required"`
+		Priority      string `json:"priority"` // Defaults to regular
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
-
-	// Validate service ownership
-	service, err := models.GetServiceByID(input.ServiceID)
-	if err != nil || service.UserID == nil || *service.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: Service does not belong to your account"})
+	if input.Priority != "" && !models.IsValidPriority(input.Priority) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority"})
 		return
 	}
 
-	// Generate QueueNumber
-	lastTicket, err := models.GetLastQueueTicketByServiceID(input.ServiceID)
-	if err != nil && err.Error() != "record not found" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate queue number", "details": err.Error()})
-		return
-	}
-	newQueueNumber := 1
-	if lastTicket != nil {
-		lastNumber, _ := strconv.Atoi(lastTicket.QueueNumber)
-		newQueueNumber = lastNumber + 1
+	// Validate service ownership; workflow steps are checked against the ticket owner when issued
+	if input.WorkflowID == nil {
+		service, err := models.GetServiceByID(input.ServiceID)
+		if err != nil || service.UserID == nil || *service.UserID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: Service does not belong to your account"})
+			return
+		}
 	}
 
-	// Generate a unique token (example using a random string)
-	token := generateToken()
-
 	// Create ticket
 	ticket := models.QueueTicket{
 		UserID:        userID,
 		ServiceID:     &input.ServiceID,
 		VenueID:       &input.VenueID,
+		WorkflowID:    input.WorkflowID,
 		CustomerName:  input.CustomerName,
 		CustomerEmail: input.CustomerEmail,
 		CustomerPhone: input.CustomerPhone,
-		QueueNumber:   strconv.Itoa(newQueueNumber),
 		Status:        "waiting", // Default status
-		Token:         token,     // Set the generated token
+		Priority:      input.Priority,
 	}
 
 	if err := models.CreateQueueTicket(&ticket); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket", "details": err.Error()})
+		status := http.StatusInternalServerError
+		if isIssuanceClosed(err) || isWorkflowUnavailable(err) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": "Failed to create ticket", "details": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, ticket)
 }
 
-// Helper function to generate a unique token using a hash and Base62 encoding
-func generateToken() string {
-	// Use a combination of timestamp and random data for uniqueness
-	data := strconv.FormatInt(time.Now().UnixNano(), 10)
-
-	// Generate a SHA256 hash of the data
-	hash := sha256.Sum256([]byte(data))
-
-	// Encode the hash in Base64
-	base64Encoded := base64.StdEncoding.EncodeToString(hash[:])
-
-	// Convert Base64 to Base62 by removing non-alphanumeric characters
-	base62Encoded := strings.Map(func(r rune) rune {
-		if (r >= '0' && r <= '9') || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') {
-			return r
-		}
-		return -1
-	}, base64Encoded)
-
-	// Return a shortened Base62-encoded string (e.g., first 8 characters)
-	return base62Encoded[:8]
-}
-
 func UpdateQueueTicketHandler(c *gin.Context) {
 	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -134,7 +105,7 @@ func UpdateQueueTicketHandler(c *gin.Context) {
 	ticket.TicketID = uint(ticketID)
 
 	if err := models.UpdateQueueTicket(&ticket, userID, isAdmin); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ticket", "details": err.Error()})
+		c.JSON(ticketStatusErrorCode(err), gin.H{"error": "Failed to update ticket", "details": err.Error()})
 		return
 	}
 
@@ -195,18 +166,219 @@ func UpdateQueueTicketStatusHandler(c *gin.Context) {
 
 	var input struct {
 		Status     string `json:"status" binding:"required"`
-		OperatorID *uint  `json:"operator_id"` // Optional operator ID
+		OperatorID *uint  `json:"operator_id"` // Optional operator ID, defaults to the caller
 		CounterID  *uint  `json:"counter_id"`  // Optional counter ID
+		Reason     string `json:"reason"`      // Optional reason recorded in the ticket history
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
 
-	if err := models.UpdateQueueTicketStatus(uint(ticketID), input.Status, input.OperatorID, input.CounterID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ticket status", "details": err.Error()})
+	// The transition is attributed to the caller unless another operator is named
+	if input.OperatorID == nil {
+		userID := c.GetUint("user_id")
+		input.OperatorID = &userID
+	}
+
+	if err := models.UpdateQueueTicketStatus(uint(ticketID), input.Status, input.OperatorID, input.CounterID, input.Reason); err != nil {
+		c.JSON(ticketStatusErrorCode(err), gin.H{"error": "Failed to update ticket status", "details": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Ticket status updated successfully"})
 }
+
+// GetQueueTicketHistoryHandler returns every status transition of a ticket
+func GetQueueTicketHistoryHandler(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	isAdmin := c.GetString("role") == "admin"
+
+	ticket, err := models.GetQueueTicketByID(uint(ticketID), userID, isAdmin)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := models.GetQueueTicketEvents(ticket.TicketID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve ticket history", "details": err.Error()})
+		return
+	}
+
+	transfers, err := models.GetTicketTransfers(ticket.TicketID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve ticket transfers", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket, "history": events, "transfers": transfers})
+}
+
+// TransferQueueTicketHandler moves a ticket to another service, counter or operator
+func TransferQueueTicketHandler(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+		return
+	}
+
+	var target models.TransferTarget
+	if err := c.ShouldBindJSON(&target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	if !canAccessTicket(c, uint(ticketID)) {
+		return
+	}
+
+	// The transfer is attributed to the caller and, for operators, the counter they work
+	operatorID := c.GetUint("user_id")
+	ticket, err := models.TransferQueueTicket(uint(ticketID), target, &operatorID, mappedCounterID(operatorID))
+	if err != nil {
+		c.JSON(transferErrorCode(err), gin.H{"error": "Failed to transfer ticket", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// canAccessTicket checks the user may act on a ticket of a venue they manage or work at,
+// responding with an error if not. Tickets outside a venue are reachable by their creator.
+func canAccessTicket(c *gin.Context, ticketID uint) bool {
+	ticket, err := models.GetQueueTicketByID(ticketID, 0, true)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return false
+	}
+
+	ownerID := ticket.UserID
+	if ticket.VenueID != nil {
+		venue, err := models.GetVenueByID(*ticket.VenueID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return false
+		}
+		ownerID = venue.UserID
+	}
+	return canAccessVenue(c, ownerID)
+}
+
+// mappedCounterID returns the counter the user is mapped to, or nil when they have none
+func mappedCounterID(userID uint) *uint {
+	mapped, err := models.GetCounterIDByUserID(int(userID))
+	if err != nil {
+		return nil
+	}
+	counterID := uint(mapped)
+	return &counterID
+}
+
+// ticketStatusErrorCode maps ticket lifecycle errors to HTTP status codes
+func ticketStatusErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidStatus):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrInvalidTransition):
+		return http.StatusConflict
+	case err.Error() == "ticket not found":
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// RecallQueueTicketHandler re-announces a called ticket, or calls back a skipped one
+func RecallQueueTicketHandler(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"` // Optional reason recorded in the ticket history
+	}
+	// The body is optional
+	_ = c.ShouldBindJSON(&input)
+
+	if !canAccessTicket(c, uint(ticketID)) {
+		return
+	}
+
+	// The recall is attributed to the caller and, for operators, the counter they work
+	operatorID := c.GetUint("user_id")
+	ticket, err := models.RecallQueueTicket(uint(ticketID), &operatorID, mappedCounterID(operatorID), input.Reason)
+	if err != nil {
+		c.JSON(ticketStatusErrorCode(err), gin.H{"error": "Failed to recall ticket", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// RejoinQueueTicketHandler puts a skipped ticket back into its queue when the customer reappears
+func RejoinQueueTicketHandler(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+		return
+	}
+
+	if !canAccessTicket(c, uint(ticketID)) {
+		return
+	}
+
+	operatorID := c.GetUint("user_id")
+	ticket, err := models.RejoinQueueTicket(uint(ticketID), &operatorID, mappedCounterID(operatorID))
+	if err != nil {
+		c.JSON(rejoinErrorCode(err), gin.H{"error": "Failed to rejoin ticket", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// rejoinErrorCode maps rejoin errors to HTTP status codes
+func rejoinErrorCode(err error) int {
+	if errors.Is(err, models.ErrRejoinNotAllowed) || isIssuanceClosed(err) {
+		return http.StatusForbidden
+	}
+	return ticketStatusErrorCode(err)
+}
+
+// transferErrorCode maps ticket transfer errors to HTTP status codes
+func transferErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidTransfer):
+		return http.StatusBadRequest
+	case err.Error() == "service not found", err.Error() == "counter not found":
+		return http.StatusNotFound
+	default:
+		return ticketStatusErrorCode(err)
+	}
+}
+
+// isIssuanceClosed reports whether a ticket was refused because the venue or service isn't taking tickets
+func isIssuanceClosed(err error) bool {
+	return errors.Is(err, models.ErrVenueClosed) ||
+		errors.Is(err, models.ErrOutsideOpeningHours) ||
+		errors.Is(err, models.ErrLastTicketCutoff) ||
+		errors.Is(err, models.ErrNoCounterScheduled)
+}
+
+// isWorkflowUnavailable reports whether a ticket was refused because its workflow can't issue tickets
+func isWorkflowUnavailable(err error) bool {
+	switch err.Error() {
+	case "workflow not found", "workflow is not active", "workflow does not belong to this venue", "workflow has no steps":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"queue-system-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRemoteJoinServices lists the services of a venue customers can join remotely
+func ListRemoteJoinServices(c *gin.Context) {
+	venueID, err := strconv.Atoi(c.Param("venue_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid venue ID"})
+		return
+	}
+
+	services, err := models.GetRemoteJoinServices(uint(venueID))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "venue not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": "Failed to fetch services", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, services)
+}
+
+// JoinQueueRemotelyHandler issues a ticket to a customer joining a queue from their phone
+func JoinQueueRemotelyHandler(c *gin.Context) {
+	venueID, err := strconv.Atoi(c.Param("venue_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid venue ID"})
+		return
+	}
+
+	var input struct {
+		ServiceID     uint   `json:"service_id" binding:"required"`
+		CustomerName  string `json:"customer_name"`
+		CustomerEmail string `json:"customer_email" binding:"omitempty,email"`
+		CustomerPhone string `json:"customer_phone" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	ticket := models.QueueTicket{
+		ServiceID:     &input.ServiceID,
+		CustomerName:  input.CustomerName,
+		CustomerEmail: input.CustomerEmail,
+		CustomerPhone: input.CustomerPhone,
+	}
+
+	if err := models.JoinQueueRemotely(uint(venueID), &ticket); err != nil {
+		c.JSON(remoteJoinErrorCode(err), gin.H{"error": "Failed to join queue", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ticket)
+}
+
+// remoteJoinErrorCode maps remote joining errors to HTTP status codes
+func remoteJoinErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrPhoneLimitReached):
+		return http.StatusTooManyRequests
+	case errors.Is(err, models.ErrQueueFull), errors.Is(err, models.ErrServeBeforeClose):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, models.ErrRemoteJoinDisabled), isIssuanceClosed(err):
+		return http.StatusForbidden
+	case err.Error() == "service not found", err.Error() == "venue not found":
+		return http.StatusNotFound
+	case err.Error() == "invalid customer_phone":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -11,6 +11,69 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// serviceInput is the body of service create and update requests. Omitted fields keep
+// the service's current value, or the default of models.NewService on create.
+type serviceInput struct {
+	ServiceName          *string `json:"service_name"`
+	VenueID              *uint   `json:"venue_id"`
+	Description          *string `json:"description"`
+	QueuePrefix          *string `json:"queue_prefix"`
+	NumberPadding        *int    `json:"number_padding"`
+	MaxPostpones         *int    `json:"max_postpones"`
+	CallPolicy           *string `json:"call_policy"`
+	RegularPerPriority   *int    `json:"regular_per_priority"`
+	PriorityAgingMinutes *int    `json:"priority_aging_minutes"`
+	RestrictVIPCounters  *bool   `json:"restrict_vip_counters"`
+	NoShowGraceSeconds   *int    `json:"no_show_grace_seconds"`
+	RejoinPolicy         *string `json:"rejoin_policy"`
+	RemoteJoinEnabled    *bool   `json:"remote_join_enabled"`
+	RemoteMaxWaiting     *int    `json:"remote_max_waiting"`
+	RemoteMaxPerPhone    *int    `json:"remote_max_per_phone"`
+	MaxBatchSize         *int    `json:"max_batch_size"`
+	SLAWaitMinutes       *int    `json:"sla_wait_minutes"`
+	SLATargetPercent     *int    `json:"sla_target_percent"`
+}
+
+// apply copies the fields that were sent onto a service
+func (input *serviceInput) apply(service *models.Service) {
+	setString := func(target *string, value *string) {
+		if value != nil {
+			*target = *value
+		}
+	}
+	setInt := func(target *int, value *int) {
+		if value != nil {
+			*target = *value
+		}
+	}
+	setBool := func(target *bool, value *bool) {
+		if value != nil {
+			*target = *value
+		}
+	}
+
+	setString(&service.ServiceName, input.ServiceName)
+	if input.VenueID != nil {
+		service.VenueID = input.VenueID
+	}
+	setString(&service.Description, input.Description)
+	setString(&service.QueuePrefix, input.QueuePrefix)
+	setInt(&service.NumberPadding, input.NumberPadding)
+	setInt(&service.MaxPostpones, input.MaxPostpones)
+	setString(&service.CallPolicy, input.CallPolicy)
+	setInt(&service.RegularPerPriority, input.RegularPerPriority)
+	setInt(&service.PriorityAgingMinutes, input.PriorityAgingMinutes)
+	setBool(&service.RestrictVIPCounters, input.RestrictVIPCounters)
+	setInt(&service.NoShowGraceSeconds, input.NoShowGraceSeconds)
+	setString(&service.RejoinPolicy, input.RejoinPolicy)
+	setBool(&service.RemoteJoinEnabled, input.RemoteJoinEnabled)
+	setInt(&service.RemoteMaxWaiting, input.RemoteMaxWaiting)
+	setInt(&service.RemoteMaxPerPhone, input.RemoteMaxPerPhone)
+	setInt(&service.MaxBatchSize, input.MaxBatchSize)
+	setInt(&service.SLAWaitMinutes, input.SLAWaitMinutes)
+	setInt(&service.SLATargetPercent, input.SLATargetPercent)
+}
+
 // ListServices retrieves all services for the user or admin
 func ListServices(c *gin.Context) {
 	claims, exists := c.Get("claims")
@@ -71,11 +134,18 @@ func CreateService(c *gin.Context) {
 		return
 	}
 
-	var service models.Service
-	if err := c.ShouldBindJSON(&service); err != nil {
+	var input serviceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if input.VenueID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "venue_id is required"})
+		return
+	}
+
+	service := models.NewService()
+	input.apply(&service)
 
 	// Set the UserID from the token claims
 	service.UserID = &userClaims.UserID
@@ -127,23 +197,23 @@ func UpdateService(c *gin.Context) {
 		return
 	}
 
-	// Bind and update the data
-	var updatedService models.Service
-	if err := c.ShouldBindJSON(&updatedService); err != nil {
+	// Bind and update the fields that were sent
+	var input serviceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Validate the venue
-	venue, err := models.GetVenueByID(*updatedService.VenueID)
-	if err != nil || venue.UserID != userClaims.UserID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid venue or access denied"})
-		return
+	if input.VenueID != nil {
+		venue, err := models.GetVenueByID(*input.VenueID)
+		if err != nil || venue.UserID != userClaims.UserID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid venue or access denied"})
+			return
+		}
 	}
 
-	service.ServiceName = updatedService.ServiceName
-	service.VenueID = updatedService.VenueID
-	service.Description = updatedService.Description
+	input.apply(service)
 
 	if err := models.UpdateService(service); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service"})
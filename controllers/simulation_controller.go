@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"queue-system-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SimulateVenueQueue replays a day of the venue, or a synthetic arrival profile, with other counters,
+// call policies or skills and reports the resulting waits. Live data is left untouched.
+func SimulateVenueQueue(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	var scenario models.SimulationScenario
+	if err := c.ShouldBindJSON(&scenario); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	result, err := models.SimulateQueue(venue.VenueID, scenario)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, models.ErrInvalidSimulation):
+			status = http.StatusBadRequest
+		case errors.Is(err, models.ErrNoServiceTimes):
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{"error": "Failed to run simulation", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
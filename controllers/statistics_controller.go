@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"queue-system-backend/models"
+	"queue-system-backend/utils"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -85,3 +89,411 @@ func (sc *StatisticsController) GetTotalServed(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, results)
 }
+
+// GetCustomerCancelled now as method
+func (sc *StatisticsController) GetCustomerCancelled(c *gin.Context) {
+	stats := &models.QueueStatistics{}
+	results, err := stats.GetCustomerCancelled(sc.getStatsFilter(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch customer cancellation statistics",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetTransfers reports the tickets transferred into and out of each service
+func (sc *StatisticsController) GetTransfers(c *gin.Context) {
+	stats := &models.QueueStatistics{}
+	results, err := stats.GetTransfers(sc.getStatsFilter(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch transfer statistics",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetCounterUtilization reports open, paused and active time per counter from the session log
+func (sc *StatisticsController) GetCounterUtilization(c *gin.Context) {
+	var req struct {
+		CounterID uint   `json:"counter_id"`
+		VenueID   uint   `json:"venue_id"`
+		From      string `json:"from"` // RFC 3339, defaults to the start of today in the venue's time zone
+		To        string `json:"to"`   // RFC 3339, defaults to now
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	from, to, _, ok := statisticsPeriod(c, req.VenueID, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	filter := models.StatisticsFilter{CounterID: req.CounterID, VenueID: req.VenueID}
+	results, err := models.GetCounterUtilization(filter, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch counter utilization",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetStatisticsSeries reports ticket counts and wait and service times over time, bucketed by
+// hour, day, week or month and grouped by venue, service, counter or operator
+func (sc *StatisticsController) GetStatisticsSeries(c *gin.Context) {
+	var req struct {
+		VenueID    uint   `json:"venue_id"`
+		ServiceID  uint   `json:"service_id"`
+		CounterID  uint   `json:"counter_id"`
+		OperatorID uint   `json:"operator_id"`
+		From       string `json:"from"`     // RFC 3339, defaults to the start of today in the venue's time zone
+		To         string `json:"to"`       // RFC 3339, defaults to now
+		Bucket     string `json:"bucket"`   // hour, day (default), week or month
+		GroupBy    string `json:"group_by"` // venue (default), service, counter or operator
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	from, to, location, ok := statisticsPeriod(c, req.VenueID, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	// Like the operator performance, only venues and operators the user can see are reported
+	filter := models.StatisticsFilter{
+		VenueID:    req.VenueID,
+		ServiceID:  req.ServiceID,
+		CounterID:  req.CounterID,
+		OperatorID: req.OperatorID,
+	}
+	if filter.VenueIDs, ok = visibleVenueIDs(c, req.VenueID); !ok {
+		return
+	}
+	if req.OperatorID != 0 || req.GroupBy == models.GroupByOperator {
+		operators, ok := visibleOperators(c, req.OperatorID)
+		if !ok {
+			return
+		}
+		filter.OperatorIDs = make([]uint, len(operators))
+		for i, operator := range operators {
+			filter.OperatorIDs[i] = operator.UserID
+		}
+	}
+
+	results, err := models.GetStatisticsSeries(models.SeriesQuery{
+		Filter:   filter,
+		From:     from,
+		To:       to,
+		Bucket:   req.Bucket,
+		GroupBy:  req.GroupBy,
+		Location: location,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidSeriesQuery) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to fetch statistics series",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetServicePerformance reports p50/p90/p95 wait and service times per service with their SLA compliance
+func (sc *StatisticsController) GetServicePerformance(c *gin.Context) {
+	var req struct {
+		VenueID   uint   `json:"venue_id"`
+		ServiceID uint   `json:"service_id"`
+		From      string `json:"from"` // RFC 3339, defaults to the start of today in the venue's time zone
+		To        string `json:"to"`   // RFC 3339, defaults to now
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	from, to, _, ok := statisticsPeriod(c, req.VenueID, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	filter := models.StatisticsFilter{VenueID: req.VenueID, ServiceID: req.ServiceID}
+	results, err := models.GetServicePerformance(filter, from, to)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidSeriesQuery) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to fetch service performance",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetOperatorPerformance reports served and skipped tickets, handling, idle and pause time per operator.
+// Admins see themselves and the operators they own; other users only themselves.
+func (sc *StatisticsController) GetOperatorPerformance(c *gin.Context) {
+	var req struct {
+		VenueID    uint   `json:"venue_id"`
+		CounterID  uint   `json:"counter_id"`
+		OperatorID uint   `json:"operator_id"` // Defaults to every operator the user can see
+		From       string `json:"from"`        // RFC 3339, defaults to the start of today in the venue's time zone
+		To         string `json:"to"`          // RFC 3339, defaults to now
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	operators, ok := visibleOperators(c, req.OperatorID)
+	if !ok {
+		return
+	}
+
+	from, to, _, ok := statisticsPeriod(c, req.VenueID, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	filter := models.StatisticsFilter{VenueID: req.VenueID, CounterID: req.CounterID}
+	results, err := models.GetOperatorPerformance(operators, filter, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch operator performance",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetArrivalHeatmap reports ticket arrivals per day of the week and hour
+func (sc *StatisticsController) GetArrivalHeatmap(c *gin.Context) {
+	var req struct {
+		VenueID   uint   `json:"venue_id"`
+		ServiceID uint   `json:"service_id"`
+		From      string `json:"from"` // RFC 3339, defaults to four weeks before today in the venue's time zone
+		To        string `json:"to"`   // RFC 3339, defaults to the start of today
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	from, to, location, ok := historyPeriod(c, req.VenueID, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	filter := models.StatisticsFilter{VenueID: req.VenueID, ServiceID: req.ServiceID}
+	heatmap, err := models.GetArrivalHeatmap(filter, from, to, location)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidSeriesQuery) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to fetch arrival heatmap",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, heatmap)
+}
+
+// GetStaffingRecommendation reports the counters a service needs in each hour of the week to meet its wait time target
+func (sc *StatisticsController) GetStaffingRecommendation(c *gin.Context) {
+	var req struct {
+		ServiceID     uint   `json:"service_id" binding:"required"`
+		From          string `json:"from"`           // RFC 3339, defaults to four weeks before today in the venue's time zone
+		To            string `json:"to"`             // RFC 3339, defaults to the start of today
+		WaitMinutes   int    `json:"wait_minutes"`   // Defaults to the service's SLA wait
+		TargetPercent int    `json:"target_percent"` // Defaults to the service's SLA target
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	service, err := models.GetServiceByID(req.ServiceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	var venueID uint
+	if service.VenueID != nil {
+		venueID = *service.VenueID
+	}
+
+	from, to, location, ok := historyPeriod(c, venueID, req.From, req.To)
+	if !ok {
+		return
+	}
+
+	recommendation, err := models.GetStaffingRecommendation(models.StaffingQuery{
+		ServiceID:     service.ServiceID,
+		From:          from,
+		To:            to,
+		Location:      location,
+		WaitMinutes:   req.WaitMinutes,
+		TargetPercent: req.TargetPercent,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, models.ErrInvalidSeriesQuery):
+			status = http.StatusBadRequest
+		case errors.Is(err, models.ErrNoServiceTimes):
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to compute staffing recommendation",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, recommendation)
+}
+
+// historyPeriod is statisticsPeriod for reports built from past weeks: without dates it covers
+// the four weeks before today, so every hour of the week occurs four times
+func historyPeriod(c *gin.Context, venueID uint, fromValue string, toValue string) (time.Time, time.Time, *time.Location, bool) {
+	from, to, location, ok := statisticsPeriod(c, venueID, fromValue, toValue)
+	if !ok {
+		return from, to, location, false
+	}
+	if toValue == "" {
+		now := time.Now().In(location)
+		to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	}
+	if fromValue == "" {
+		from = to.AddDate(0, 0, -28)
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return from, to, location, false
+	}
+	return from, to, location, true
+}
+
+// visibleOperators lists the operators whose statistics the user can see, responding with an error if
+// operatorID is set and not one of them. Admins see themselves and the operators they own; other users
+// only themselves.
+func visibleOperators(c *gin.Context, operatorID uint) ([]models.User, bool) {
+	claims, ok := c.MustGet("claims").(*utils.Claims)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid token claims"})
+		return nil, false
+	}
+
+	var operators []models.User
+	var err error
+	if strings.EqualFold(claims.Role, "admin") {
+		operators, err = models.ListUsersByAdmin(claims.UserID)
+	} else {
+		operators, err = models.ListUsersByID(claims.UserID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch operators", "details": err.Error()})
+		return nil, false
+	}
+
+	if operatorID != 0 {
+		var selected []models.User
+		for _, operator := range operators {
+			if operator.UserID == operatorID {
+				selected = append(selected, operator)
+			}
+		}
+		if len(selected) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return nil, false
+		}
+		operators = selected
+	}
+	return operators, true
+}
+
+// visibleVenueIDs checks the user can access the requested venue, responding with an error if not.
+// Without a venue it lists the venues the user can access, or nil for admins who reach every venue.
+func visibleVenueIDs(c *gin.Context, venueID uint) ([]uint, bool) {
+	if venueID != 0 {
+		venue, err := models.GetVenueByID(venueID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Venue not found"})
+			return nil, false
+		}
+		return nil, canAccessVenue(c, venue.UserID)
+	}
+
+	claims, ok := c.MustGet("claims").(*utils.Claims)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid token claims"})
+		return nil, false
+	}
+	if strings.EqualFold(claims.Role, "admin") {
+		return nil, true
+	}
+
+	// Operators see the venues of their owner
+	ownerID := claims.UserID
+	if strings.EqualFold(claims.Role, "operator") {
+		user, err := models.GetUserByID(claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user", "details": err.Error()})
+			return nil, false
+		}
+		if user.OwnerID != nil {
+			ownerID = *user.OwnerID
+		}
+	}
+	venues, err := models.GetVenuesByUser(ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch venues", "details": err.Error()})
+		return nil, false
+	}
+	venueIDs := make([]uint, len(venues))
+	for i, venue := range venues {
+		venueIDs[i] = venue.VenueID
+	}
+	return venueIDs, true
+}
+
+// statisticsPeriod parses a statistics request's period in the time zone of its venue, or the
+// server's without one, responding with an error if it is invalid
+func statisticsPeriod(c *gin.Context, venueID uint, fromValue string, toValue string) (time.Time, time.Time, *time.Location, bool) {
+	location := time.Local
+	if venueID != 0 {
+		venue, err := models.GetVenueByID(venueID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Venue not found"})
+			return time.Time{}, time.Time{}, nil, false
+		}
+		location = venue.Location()
+	}
+
+	from, to, err := parsePeriod(fromValue, toValue, location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return time.Time{}, time.Time{}, nil, false
+	}
+	return from, to, location, true
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"queue-system-backend/models"
+	"queue-system-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListVenueClosures retrieves a venue's holiday and closure calendar, optionally from a date on
+func ListVenueClosures(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	closures, err := models.GetVenueClosures(venue.VenueID, c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch closures", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, closures)
+}
+
+// CreateVenueClosure adds a closure to a venue's calendar
+func CreateVenueClosure(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	var closure models.VenueClosure
+	if err := c.ShouldBindJSON(&closure); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	closure.ClosureID = 0
+	closure.VenueID = venue.VenueID
+
+	if err := models.CreateVenueClosure(&closure); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create closure", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, closure)
+}
+
+// UpdateVenueClosure updates a closure of a venue
+func UpdateVenueClosure(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	closureID, err := strconv.Atoi(c.Param("closure_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid closure ID"})
+		return
+	}
+
+	closure, err := models.GetVenueClosureByID(venue.VenueID, uint(closureID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		StartDate string `json:"start_date" binding:"required"`
+		EndDate   string `json:"end_date"`
+		Reason    string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	closure.StartDate = models.Date(input.StartDate)
+	closure.EndDate = models.Date(input.EndDate)
+	closure.Reason = input.Reason
+
+	if err := models.UpdateVenueClosure(closure); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update closure", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, closure)
+}
+
+// DeleteVenueClosure deletes a closure of a venue
+func DeleteVenueClosure(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	closureID, err := strconv.Atoi(c.Param("closure_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid closure ID"})
+		return
+	}
+
+	if err := models.DeleteVenueClosure(venue.VenueID, uint(closureID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete closure", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Closure deleted successfully"})
+}
+
+// accessibleVenue loads the venue from the :id parameter and checks the user may manage it
+func accessibleVenue(c *gin.Context) (*models.Venue, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid venue ID"})
+		return nil, false
+	}
+
+	venue, err := models.GetVenueByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if !canAccessVenue(c, venue.UserID) {
+		return nil, false
+	}
+	return venue, true
+}
+
+// canAccessVenue checks the user may manage a venue of the given owner, responding with an error if not.
+// Admins reach every venue, owners their own and operators those of their owner.
+func canAccessVenue(c *gin.Context, ownerID uint) bool {
+	userClaims, ok := c.MustGet("claims").(*utils.Claims)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid token claims"})
+		return false
+	}
+
+	switch {
+	case strings.ToLower(userClaims.Role) == "admin", ownerID == userClaims.UserID:
+		return true
+	case strings.ToLower(userClaims.Role) == "operator":
+		user, err := models.GetUserByID(userClaims.UserID)
+		if err == nil && user.OwnerID != nil && *user.OwnerID == ownerID {
+			return true
+		}
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	return false
+}
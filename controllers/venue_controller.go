@@ -76,7 +76,7 @@ func CreateVenue(c *gin.Context) {
 	venue.UserID = userClaims.UserID
 
 	if err := venue.CreateVenue(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create venue"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create venue", "details": err.Error()})
 		return
 	}
 
@@ -156,7 +156,7 @@ func UpdateVenue(c *gin.Context) {
 	}
 
 	if err := venue.UpdateVenue(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update venue"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update venue", "details": err.Error()})
 		return
 	}
 
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -36,14 +37,95 @@ func GetQueueTicketByTokenHandler(c *gin.Context) {
 		return
 	}
 
+	estimate, err := models.EstimateQueueTicketWait(ticket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Current and remaining steps for workflow tickets, null otherwise
+	journey, err := models.GetTicketJourney(ticket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"ticket":             ticket,
 		"service_name":       serviceName,
 		"venue_name":         venueName,
 		"average_queue_time": averageQueueTime,
+		"estimate":           estimate,
+		"journey":            journey,
 	})
 }
 
+// CancelQueueTicketByTokenHandler lets the customer cancel their own ticket
+func CancelQueueTicketByTokenHandler(c *gin.Context) {
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	// The body is optional
+	_ = c.ShouldBindJSON(&input)
+
+	ticket, err := models.CancelQueueTicketByToken(c.Param("token"), input.Reason)
+	if err != nil {
+		c.JSON(ticketStatusErrorCode(err), gin.H{"error": "Failed to cancel ticket", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket cancelled successfully", "ticket": ticket})
+}
+
+// PostponeQueueTicketByTokenHandler lets a late customer move their ticket back in the queue
+func PostponeQueueTicketByTokenHandler(c *gin.Context) {
+	var input struct {
+		Places int `json:"places" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	ticket, err := models.PostponeQueueTicketByToken(c.Param("token"), input.Places)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, models.ErrPostponeLimitReached):
+			status = http.StatusConflict
+		case err.Error() == "ticket not found":
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": "Failed to postpone ticket", "details": err.Error()})
+		return
+	}
+
+	estimate, err := models.EstimateQueueTicketWait(ticket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket postponed successfully", "ticket": ticket, "estimate": estimate})
+}
+
+// RejoinQueueTicketByTokenHandler lets a customer whose ticket was skipped rejoin the queue
+func RejoinQueueTicketByTokenHandler(c *gin.Context) {
+	ticket, err := models.RejoinQueueTicketByToken(c.Param("token"))
+	if err != nil {
+		c.JSON(rejoinErrorCode(err), gin.H{"error": "Failed to rejoin queue", "details": err.Error()})
+		return
+	}
+
+	estimate, err := models.EstimateQueueTicketWait(ticket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket rejoined the queue", "ticket": ticket, "estimate": estimate})
+}
+
 func GetWaitingQueueTicketsHandler(c *gin.Context) {
 	venueID := c.Query("venue_id")
 	serviceID := c.Query("service_id")
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"queue-system-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// workflowInput is the body of workflow create and update requests
+type workflowInput struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Active      *bool  `json:"active"` // Defaults to true
+	Steps       []struct {
+		ServiceID uint   `json:"service_id" binding:"required"`
+		Name      string `json:"name"`
+	} `json:"steps" binding:"required"`
+}
+
+// apply copies the input onto a workflow
+func (input *workflowInput) apply(workflow *models.Workflow) {
+	workflow.Name = input.Name
+	workflow.Description = input.Description
+	workflow.Active = input.Active == nil || *input.Active
+	workflow.Steps = make([]models.WorkflowStep, len(input.Steps))
+	for i, step := range input.Steps {
+		workflow.Steps[i] = models.WorkflowStep{ServiceID: step.ServiceID, Name: step.Name}
+	}
+}
+
+// ListWorkflows retrieves a venue's workflows with their steps
+func ListWorkflows(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	workflows, err := models.GetWorkflowsByVenue(venue.VenueID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workflows", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, workflows)
+}
+
+// GetWorkflow retrieves a workflow of a venue
+func GetWorkflow(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	workflow, ok := venueWorkflow(c, venue)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, workflow)
+}
+
+// CreateWorkflow adds a workflow to a venue
+func CreateWorkflow(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	var input workflowInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	workflow := models.Workflow{UserID: venue.UserID, VenueID: venue.VenueID}
+	input.apply(&workflow)
+
+	if err := models.CreateWorkflow(&workflow); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create workflow", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, workflow)
+}
+
+// UpdateWorkflow updates a workflow of a venue and replaces its steps
+func UpdateWorkflow(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	workflow, ok := venueWorkflow(c, venue)
+	if !ok {
+		return
+	}
+
+	var input workflowInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	input.apply(workflow)
+
+	if err := models.UpdateWorkflow(workflow); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update workflow", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, workflow)
+}
+
+// DeleteWorkflow deletes a workflow of a venue
+func DeleteWorkflow(c *gin.Context) {
+	venue, ok := accessibleVenue(c)
+	if !ok {
+		return
+	}
+
+	workflow, ok := venueWorkflow(c, venue)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteWorkflow(workflow.WorkflowID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workflow", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workflow deleted successfully"})
+}
+
+// venueWorkflow loads the workflow from the :workflow_id parameter and checks it belongs to the venue
+func venueWorkflow(c *gin.Context, venue *models.Venue) (*models.Workflow, bool) {
+	workflowID, err := strconv.Atoi(c.Param("workflow_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow ID"})
+		return nil, false
+	}
+
+	workflow, err := models.GetWorkflowByID(uint(workflowID))
+	if err != nil || workflow.VenueID != venue.VenueID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "workflow not found"})
+		return nil, false
+	}
+	return workflow, true
+}
@@ -17,7 +17,8 @@ func ConnectDB() {
 		log.Fatal("DB_DSN is not set in the environment variables.")
 	}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError maps driver errors such as duplicate keys to gorm.ErrDuplicatedKey
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
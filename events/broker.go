@@ -0,0 +1,163 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the queue
+const (
+	TypeDisplayUpdated    = "display.updated"
+	TypeTicketCreated     = "ticket.created"
+	TypeTicketPostponed   = "ticket.postponed"
+	TypeTicketTransferred = "ticket.transferred"
+	TypeTicketAdvanced    = "ticket.advanced"    // A workflow ticket moved on to its next step
+	TypeTicketReannounced = "ticket.reannounced" // A called ticket was recalled to the counter
+	TypeCounterStatus     = "counter.status"
+)
+
+// TicketEventType returns the event type published when a ticket enters status, e.g. "ticket.called"
+func TicketEventType(status string) string {
+	return "ticket." + status
+}
+
+// Event is a queue change pushed to display screens and operator consoles
+type Event struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	VenueID   uint        `json:"venue_id"`
+	ServiceID uint        `json:"service_id"`
+	CounterID uint        `json:"counter_id"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Filter selects the events a subscriber receives. Zero fields match everything.
+type Filter struct {
+	VenueID   uint
+	ServiceID uint
+}
+
+// Matches reports whether the event passes the filter.
+// Events without a venue or service (e.g. a global reset) reach every subscriber.
+func (f Filter) Matches(e Event) bool {
+	if f.VenueID != 0 && e.VenueID != 0 && e.VenueID != f.VenueID {
+		return false
+	}
+	if f.ServiceID != 0 && e.ServiceID != 0 && e.ServiceID != f.ServiceID {
+		return false
+	}
+	return true
+}
+
+// subscriberBuffer is how many undelivered events a subscriber may have before it is dropped
+const subscriberBuffer = 64
+
+// Broker fans out events to subscribers and keeps a bounded history so
+// reconnecting clients can catch up from their last seen event ID.
+// Event IDs restart when the process restarts.
+type Broker struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []Event
+	historySize int
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events matching its filter on C.
+// C is closed when the subscription ends or falls too far behind.
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	filter Filter
+	broker *Broker
+}
+
+// NewBroker creates a broker remembering the last historySize events
+func NewBroker(historySize int) *Broker {
+	return &Broker{
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Default is the process-wide broker used by the models and controllers
+var Default = NewBroker(1000)
+
+// Publish sends an event through the default broker
+func Publish(e Event) Event {
+	return Default.Publish(e)
+}
+
+// Subscribe subscribes to the default broker
+func Subscribe(filter Filter, lastEventID uint64) (*Subscription, []Event) {
+	return Default.Subscribe(filter, lastEventID)
+}
+
+// Publish assigns the event an ID, stores it in the history and delivers it to matching subscribers.
+// Subscribers that cannot keep up are closed; they can reconnect and replay from the history.
+func (b *Broker) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	e.ID = b.nextID
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	b.history = append(b.history, e)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.Matches(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			b.remove(sub)
+		}
+	}
+
+	return e
+}
+
+// Subscribe registers a subscriber and returns the events after lastEventID that it missed
+func (b *Broker) Subscribe(filter Filter, lastEventID uint64) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []Event
+	if lastEventID > 0 {
+		for _, e := range b.history {
+			if e.ID > lastEventID && filter.Matches(e) {
+				missed = append(missed, e)
+			}
+		}
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, broker: b}
+	b.subscribers[sub] = struct{}{}
+
+	return sub, missed
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// remove drops a subscriber; callers must hold b.mu
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.ch)
+}
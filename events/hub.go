@@ -0,0 +1,99 @@
+package events
+
+import (
+	"sync"
+)
+
+// clientBuffer is how many undelivered events a hub client may have before events are dropped for it
+const clientBuffer = 64
+
+// Client is a connection joined to a venue room of the hub
+type Client struct {
+	VenueID uint
+	Send    chan Event
+}
+
+// Hub groups live connections into per-venue rooms and fans out broker events to them
+type Hub struct {
+	mu     sync.Mutex
+	rooms  map[uint]map[*Client]struct{}
+	broker *Broker
+}
+
+// NewHub creates a hub fed by the given broker
+func NewHub(broker *Broker) *Hub {
+	return &Hub{
+		rooms:  make(map[uint]map[*Client]struct{}),
+		broker: broker,
+	}
+}
+
+// DefaultHub is the process-wide hub fed by the default broker
+var DefaultHub = NewHub(Default)
+
+// Run forwards broker events to the rooms until the process exits
+func (h *Hub) Run() {
+	for {
+		subscription, _ := h.broker.Subscribe(Filter{}, 0)
+		for event := range subscription.C {
+			h.broadcast(event)
+		}
+		// The broker dropped the subscription; subscribe again
+	}
+}
+
+// Join adds a new client to the venue's room
+func (h *Hub) Join(venueID uint) *Client {
+	client := &Client{VenueID: venueID, Send: make(chan Event, clientBuffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[venueID]
+	if !ok {
+		room = make(map[*Client]struct{})
+		h.rooms[venueID] = room
+	}
+	room[client] = struct{}{}
+
+	return client
+}
+
+// Leave removes the client from its room and closes its Send channel
+func (h *Hub) Leave(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[client.VenueID]
+	if !ok {
+		return
+	}
+	if _, ok := room[client]; !ok {
+		return
+	}
+
+	delete(room, client)
+	if len(room) == 0 {
+		delete(h.rooms, client.VenueID)
+	}
+	close(client.Send)
+}
+
+// broadcast delivers an event to its venue's room, or to every room when it has no venue.
+// Slow clients miss the event rather than blocking the hub.
+func (h *Hub) broadcast(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for venueID, room := range h.rooms {
+		if event.VenueID != 0 && event.VenueID != venueID {
+			continue
+		}
+		for client := range room {
+			select {
+			case client.Send <- event:
+			default:
+			}
+		}
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"log"
 	"os"
 	"queue-system-backend/database"
+	"queue-system-backend/events"
+	"queue-system-backend/models"
 	"queue-system-backend/routes"
+	"queue-system-backend/utils"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -19,6 +23,17 @@ func main() {
 		log.Fatal("❌ Database connection is not initialized.")
 	}
 
+	// Create tables and columns added after the initial schema
+	if err := models.AutoMigrate(); err != nil {
+		log.Fatalf("❌ Failed to migrate database: %v", err)
+	}
+
+	// Fan out queue events to the operator console rooms
+	go events.DefaultHub.Run()
+	go models.RunCounterAutoClose(time.Minute)
+	go models.RunNoShowAutoSkip(10 * time.Second)
+	go models.RunAppointmentNoShows(time.Minute)
+
 	// Initialize controllers
 	//statsController := controllers.NewStatisticsController(database.DB)
 
@@ -27,9 +42,9 @@ func main() {
 	//r.Use(cors.Default())
 
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:8081", "http://localhost", "https://reqbin.com/"}, // Allow Vue frontend
+		AllowOrigins:     utils.AllowedOrigins(), // Allow Vue frontend, see ALLOWED_ORIGINS
 		AllowMethods:     []string{"GET", "POST", "OPTIONS", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", "X-Kiosk-Key"},
 		AllowCredentials: true,
 	}))
 
@@ -68,6 +83,21 @@ func main() {
 	// Setup statistics routes
 	routes.SetupStatisticsRoutes(r)
 
+	// Register kiosk device and kiosk issuance routes
+	routes.RegisterKioskRoutes(r)
+
+	// Register operator console WebSocket routes
+	routes.RegisterOperatorConsoleRoutes(r)
+
+	// Register counter session routes
+	routes.RegisterCounterSessionRoutes(r)
+
+	// Register appointment booking and check-in routes
+	routes.RegisterAppointmentRoutes(r)
+
+	// Register public remote queue joining routes
+	routes.RegisterRemoteJoinRoutes(r)
+
 	// Define port (with fallback to default port 8081)
 	port := os.Getenv("PORT")
 	if port == "" {
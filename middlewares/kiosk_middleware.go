@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+
+	"queue-system-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KioskMiddleware authenticates a kiosk device by its X-Kiosk-Key header
+func KioskMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-Kiosk-Key")
+		if apiKey == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Kiosk key is required"})
+			c.Abort()
+			return
+		}
+
+		device, err := models.GetKioskDeviceByAPIKey(apiKey)
+		if err != nil {
+			log.Printf("🔴 Kiosk key error: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or inactive kiosk"})
+			c.Abort()
+			return
+		}
+
+		if err := models.TouchKioskDevice(device.KioskID); err != nil {
+			log.Printf("🟠 Failed to update kiosk last seen: %v", err)
+		}
+
+		// Set the kiosk in the context
+		c.Set("kiosk", device)
+		c.Set("venue_id", device.VenueID)
+
+		c.Next()
+	}
+}
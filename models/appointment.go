@@ -0,0 +1,370 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"queue-system-backend/database"
+	"queue-system-backend/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Appointment statuses
+const (
+	AppointmentBooked    = "booked"
+	AppointmentCheckedIn = "checked_in"
+	AppointmentCancelled = "cancelled"
+	AppointmentNoShow    = "no_show"
+)
+
+// activeAppointmentStatuses are the statuses that take up a slot's capacity
+var activeAppointmentStatuses = []string{AppointmentBooked, AppointmentCheckedIn}
+
+const (
+	// bookingCodeLength is the length of the code customers check in with
+	bookingCodeLength = 8
+	// appointmentQRPrefix prefixes the booking code in the check-in QR payload
+	appointmentQRPrefix = "APPT:"
+	// appointmentCheckInEarly is how long before its slot an appointment can be checked in
+	appointmentCheckInEarly = 30 * time.Minute
+)
+
+var (
+	// ErrSlotUnavailable is returned when booking a slot that doesn't exist or has already started
+	ErrSlotUnavailable = errors.New("appointment slot is not available")
+	// ErrSlotFull is returned when booking a slot that has no capacity left
+	ErrSlotFull = errors.New("appointment slot is fully booked")
+	// ErrAppointmentNotBooked is returned when an appointment is no longer in the booked status
+	ErrAppointmentNotBooked = errors.New("appointment is not booked")
+	// ErrCheckInWindow is returned when checking in too early or after the slot ended
+	ErrCheckInWindow = errors.New("appointment cannot be checked in at this time")
+)
+
+// Appointment is a booked time slot of a service. Checking in turns it into a QueueTicket
+// with appointment priority.
+type Appointment struct {
+	AppointmentID uint       `json:"appointment_id" gorm:"primaryKey;autoIncrement"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"` // Owner of the service
+	VenueID       uint       `json:"venue_id" gorm:"not null;index"`
+	ServiceID     uint       `json:"service_id" gorm:"not null;index:idx_appointment_slot"`
+	TemplateID    uint       `json:"template_id"`
+	SlotStart     time.Time  `json:"slot_start" gorm:"not null;index:idx_appointment_slot"`
+	SlotEnd       time.Time  `json:"slot_end" gorm:"not null"`
+	CustomerName  string     `json:"customer_name" gorm:"size:255;not null"`
+	CustomerEmail string     `json:"customer_email" gorm:"size:255"`
+	CustomerPhone string     `json:"customer_phone" gorm:"size:20"`
+	BookingCode   string     `json:"booking_code" gorm:"size:16;uniqueIndex:idx_appointment_booking_code"`
+	Status        string     `json:"status" gorm:"size:20;not null;default:'booked'"`
+	TicketID      *uint      `json:"ticket_id"` // Ticket issued at check-in
+	CheckedInAt   *time.Time `json:"checked_in_at"`
+	CancelledAt   *time.Time `json:"cancelled_at"`
+	CancelledBy   string     `json:"cancelled_by" gorm:"size:20"` // "customer" or "operator"
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName ensures GORM uses the correct table name
+func (Appointment) TableName() string {
+	return "Appointments"
+}
+
+// QRPayload returns the content of the check-in QR code of the appointment
+func (a *Appointment) QRPayload() string {
+	return appointmentQRPrefix + a.BookingCode
+}
+
+// BookingCodeFromQR extracts the booking code from a scanned check-in QR payload
+func BookingCodeFromQR(payload string) string {
+	return strings.TrimPrefix(strings.TrimSpace(payload), appointmentQRPrefix)
+}
+
+// BookAppointment books a slot of the service starting at appointment.SlotStart
+// and assigns the appointment its booking code
+func BookAppointment(appointment *Appointment) error {
+	if strings.TrimSpace(appointment.CustomerName) == "" {
+		return errors.New("customer_name is required")
+	}
+	if appointment.CustomerEmail == "" && appointment.CustomerPhone == "" {
+		return errors.New("customer_email or customer_phone is required")
+	}
+
+	service, err := GetServiceByID(appointment.ServiceID)
+	if err != nil {
+		return err
+	}
+	if service.VenueID == nil || service.UserID == nil {
+		return errors.New("service does not take appointments")
+	}
+	venue, err := GetVenueByID(*service.VenueID)
+	if err != nil {
+		return err
+	}
+
+	appointment.AppointmentID = 0
+	appointment.UserID = *service.UserID
+	appointment.VenueID = venue.VenueID
+	appointment.Status = AppointmentBooked
+	appointment.TicketID = nil
+
+	insert := func(tx *gorm.DB) error {
+		slot, err := reserveAppointmentSlot(tx, venue, service.ServiceID, appointment.SlotStart, 0)
+		if err != nil {
+			return err
+		}
+		appointment.TemplateID = slot.TemplateID
+		appointment.SlotStart = slot.Start
+		appointment.SlotEnd = slot.End
+		return tx.Create(appointment).Error
+	}
+
+	// Retry on the rare booking code collision, like ticket tokens
+	for attempt := 1; ; attempt++ {
+		appointment.BookingCode, err = utils.GenerateCode(bookingCodeLength)
+		if err != nil {
+			return errors.New("failed to generate booking code")
+		}
+
+		err = database.DB.Transaction(insert)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) || attempt == maxTokenAttempts {
+			return err
+		}
+	}
+}
+
+// reserveAppointmentSlot checks the slot exists, hasn't started and has capacity left within tx.
+// The template row is locked so concurrent bookings of a slot are serialised.
+// The appointment being rescheduled, if any, doesn't count against the capacity.
+func reserveAppointmentSlot(tx *gorm.DB, venue *Venue, serviceID uint, start time.Time, excludeID uint) (*AppointmentSlot, error) {
+	slot, err := findAppointmentSlot(tx, venue, serviceID, start)
+	if err != nil {
+		return nil, err
+	}
+	if !slot.Start.After(time.Now()) {
+		return nil, ErrSlotUnavailable
+	}
+
+	var template AppointmentSlotTemplate
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&template, slot.TemplateID).Error; err != nil {
+		return nil, err
+	}
+
+	var booked int64
+	err = tx.Model(&Appointment{}).
+		Where("service_id = ? AND slot_start = ? AND status IN ? AND appointment_id <> ?",
+			serviceID, slot.Start, activeAppointmentStatuses, excludeID).
+		Count(&booked).Error
+	if err != nil {
+		return nil, err
+	}
+	if booked >= int64(slot.Capacity) {
+		return nil, ErrSlotFull
+	}
+	return slot, nil
+}
+
+// GetAppointmentByCode retrieves an appointment by its booking code
+func GetAppointmentByCode(code string) (*Appointment, error) {
+	return getAppointment(database.DB, "booking_code = ?", strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// GetAppointmentByID retrieves an appointment by ID
+func GetAppointmentByID(appointmentID uint) (*Appointment, error) {
+	return getAppointment(database.DB, "appointment_id = ?", appointmentID)
+}
+
+// getAppointment loads the appointment matching the condition
+func getAppointment(tx *gorm.DB, query string, value interface{}) (*Appointment, error) {
+	if value == "" {
+		return nil, errors.New("appointment not found")
+	}
+	var appointment Appointment
+	if err := tx.Where(query, value).First(&appointment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("appointment not found")
+		}
+		return nil, err
+	}
+	return &appointment, nil
+}
+
+// GetAppointments lists a venue's appointments on a date (YYYY-MM-DD, in the venue's time zone), optionally for one service
+func GetAppointments(venue *Venue, serviceID uint, date string) ([]Appointment, error) {
+	day, err := time.ParseInLocation(dateLayout, date, venue.Location())
+	if err != nil {
+		return nil, errors.New("invalid date format, expected YYYY-MM-DD")
+	}
+
+	query := database.DB.Where("venue_id = ? AND slot_start >= ? AND slot_start < ?", venue.VenueID, day, day.AddDate(0, 0, 1))
+	if serviceID != 0 {
+		query = query.Where("service_id = ?", serviceID)
+	}
+	var appointments []Appointment
+	err = query.Order("slot_start ASC, appointment_id ASC").Find(&appointments).Error
+	return appointments, err
+}
+
+// RescheduleAppointment moves a booked appointment to another slot of its service
+func RescheduleAppointment(code string, start time.Time) (*Appointment, error) {
+	appointment, err := GetAppointmentByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	venue, err := GetVenueByID(appointment.VenueID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAppointment(tx, appointment); err != nil {
+			return err
+		}
+		if appointment.Status != AppointmentBooked {
+			return ErrAppointmentNotBooked
+		}
+
+		slot, err := reserveAppointmentSlot(tx, venue, appointment.ServiceID, start, appointment.AppointmentID)
+		if err != nil {
+			return err
+		}
+		appointment.TemplateID = slot.TemplateID
+		appointment.SlotStart = slot.Start
+		appointment.SlotEnd = slot.End
+		return tx.Save(appointment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+// CancelAppointment cancels a booked appointment, freeing its slot
+func CancelAppointment(appointment *Appointment, byCustomer bool) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAppointment(tx, appointment); err != nil {
+			return err
+		}
+		if appointment.Status != AppointmentBooked {
+			return ErrAppointmentNotBooked
+		}
+
+		now := time.Now()
+		appointment.Status = AppointmentCancelled
+		appointment.CancelledAt = &now
+		appointment.CancelledBy = "operator"
+		if byCustomer {
+			appointment.CancelledBy = "customer"
+		}
+		return tx.Save(appointment).Error
+	})
+}
+
+// MarkAppointmentNoShow records that the customer of a booked appointment didn't come
+func MarkAppointmentNoShow(appointment *Appointment) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAppointment(tx, appointment); err != nil {
+			return err
+		}
+		if appointment.Status != AppointmentBooked {
+			return ErrAppointmentNotBooked
+		}
+		appointment.Status = AppointmentNoShow
+		return tx.Save(appointment).Error
+	})
+}
+
+// MarkMissedAppointments marks the booked appointments whose slot ended before now as no-shows
+// and returns how many were marked
+func MarkMissedAppointments(now time.Time) (int64, error) {
+	result := database.DB.Model(&Appointment{}).
+		Where("status = ? AND slot_end < ?", AppointmentBooked, now).
+		Update("status", AppointmentNoShow)
+	return result.RowsAffected, result.Error
+}
+
+// RunAppointmentNoShows marks missed appointments every interval. It blocks, so run it in its own goroutine.
+func RunAppointmentNoShows(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if marked, err := MarkMissedAppointments(time.Now()); err != nil {
+			log.Printf("🔴 Failed to mark missed appointments: %v", err)
+		} else if marked > 0 {
+			log.Printf("🟢 Marked %d missed appointment(s) as no-show", marked)
+		}
+	}
+}
+
+// CheckInAppointment turns a booked appointment into a QueueTicket with appointment priority.
+// Check-in opens appointmentCheckInEarly before the slot and closes when the slot ends.
+// Kiosk check-ins must be at the appointment's venue.
+func CheckInAppointment(code string, kiosk *KioskDevice) (*Appointment, *QueueTicket, error) {
+	appointment, err := GetAppointmentByCode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kiosk != nil && kiosk.VenueID != appointment.VenueID {
+		return nil, nil, errors.New("appointment is at another venue")
+	}
+	if appointment.Status != AppointmentBooked {
+		return nil, nil, ErrAppointmentNotBooked
+	}
+
+	now := time.Now()
+	if now.Before(appointment.SlotStart.Add(-appointmentCheckInEarly)) || !now.Before(appointment.SlotEnd) {
+		return nil, nil, fmt.Errorf("%w: check-in is open from %s until %s", ErrCheckInWindow,
+			appointment.SlotStart.Add(-appointmentCheckInEarly).Format(time.RFC3339), appointment.SlotEnd.Format(time.RFC3339))
+	}
+
+	ticket := QueueTicket{
+		UserID:        appointment.UserID,
+		ServiceID:     &appointment.ServiceID,
+		VenueID:       &appointment.VenueID,
+		CustomerName:  appointment.CustomerName,
+		CustomerEmail: appointment.CustomerEmail,
+		CustomerPhone: appointment.CustomerPhone,
+		Priority:      PriorityAppointment,
+	}
+	if kiosk != nil {
+		ticket.KioskID = &kiosk.KioskID
+	}
+	// The slot was offered within the venue's hours, so check-ins before opening or after the
+	// last ticket cutoff are still accepted. The appointment is claimed in the ticket's transaction
+	// so a double scan can't issue two tickets and a failed issue leaves it booked.
+	err = createQueueTicket(&ticket, ticketIssue{
+		booked: true,
+		guard: func(tx *gorm.DB) error {
+			result := tx.Model(&Appointment{}).
+				Where("appointment_id = ? AND status = ?", appointment.AppointmentID, AppointmentBooked).
+				Updates(map[string]interface{}{"status": AppointmentCheckedIn, "checked_in_at": now})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrAppointmentNotBooked
+			}
+			return nil
+		},
+		issued: func(tx *gorm.DB) error {
+			return tx.Model(&Appointment{}).
+				Where("appointment_id = ?", appointment.AppointmentID).
+				Update("ticket_id", ticket.TicketID).Error
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	appointment.Status = AppointmentCheckedIn
+	appointment.CheckedInAt = &now
+	appointment.TicketID = &ticket.TicketID
+	return appointment, &ticket, nil
+}
+
+// lockAppointment reloads an appointment with a row lock for the rest of tx
+func lockAppointment(tx *gorm.DB, appointment *Appointment) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(appointment, appointment.AppointmentID).Error
+}
@@ -0,0 +1,276 @@
+package models
+
+import (
+	"errors"
+	"queue-system-backend/database"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AppointmentSlotTemplate generates bookable appointment slots for a service on the days it applies to.
+// Slots are laid out from StartTime to EndTime and only kept within the venue's opening hours.
+type AppointmentSlotTemplate struct {
+	TemplateID  uint      `json:"template_id" gorm:"primaryKey;autoIncrement"`
+	ServiceID   uint      `json:"service_id" gorm:"not null;index"`
+	VenueID     uint      `json:"venue_id" gorm:"not null;index"`
+	Weekdays    string    `json:"weekdays" gorm:"size:20"`                  // Comma-separated days, 0 = Sunday; empty applies every day
+	StartTime   string    `json:"start_time" gorm:"type:time;default:null"` // First slot; defaults to the venue's OpenTime
+	EndTime     string    `json:"end_time" gorm:"type:time;default:null"`   // Slots end by this time; defaults to the venue's CloseTime
+	SlotMinutes int       `json:"slot_minutes" gorm:"not null"`             // Length of each slot
+	Capacity    int       `json:"capacity" gorm:"not null;default:1"`       // Appointments bookable per slot
+	Active      bool      `json:"active" gorm:"not null"`                   // Inactive templates offer no slots
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName ensures GORM uses the correct table name
+func (AppointmentSlotTemplate) TableName() string {
+	return "AppointmentSlotTemplates"
+}
+
+// AppointmentSlot is a bookable time slot generated from a template
+type AppointmentSlot struct {
+	TemplateID uint      `json:"template_id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Capacity   int       `json:"capacity"`
+	Booked     int       `json:"booked"`
+	Available  int       `json:"available"`
+}
+
+// validate checks the template's settings
+func (t *AppointmentSlotTemplate) validate() error {
+	if t.SlotMinutes <= 0 {
+		return errors.New("slot_minutes must be positive")
+	}
+	if t.Capacity <= 0 {
+		return errors.New("capacity must be positive")
+	}
+	if _, err := t.weekdays(); err != nil {
+		return err
+	}
+	for _, clock := range []string{t.StartTime, t.EndTime} {
+		if clock == "" {
+			continue
+		}
+		if _, err := time.Parse(clockLayout, clock); err != nil {
+			return errors.New("invalid start_time or end_time, expected HH:MM:SS")
+		}
+	}
+	return nil
+}
+
+// weekdays parses Weekdays, returning nil when the template applies every day
+func (t *AppointmentSlotTemplate) weekdays() (map[time.Weekday]bool, error) {
+	if strings.TrimSpace(t.Weekdays) == "" {
+		return nil, nil
+	}
+	days := map[time.Weekday]bool{}
+	for _, part := range strings.Split(t.Weekdays, ",") {
+		day, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || day < 0 || day > 6 {
+			return nil, errors.New("weekdays must list days from 0 (Sunday) to 6 (Saturday)")
+		}
+		days[time.Weekday(day)] = true
+	}
+	return days, nil
+}
+
+// CreateAppointmentSlotTemplate adds a slot template to a service
+func CreateAppointmentSlotTemplate(template *AppointmentSlotTemplate) error {
+	if err := template.validate(); err != nil {
+		return err
+	}
+	return database.DB.Create(template).Error
+}
+
+// UpdateAppointmentSlotTemplate saves changes to a slot template. Existing bookings are kept.
+func UpdateAppointmentSlotTemplate(template *AppointmentSlotTemplate) error {
+	if err := template.validate(); err != nil {
+		return err
+	}
+	return database.DB.Save(template).Error
+}
+
+// DeleteAppointmentSlotTemplate deletes a slot template of a service. Existing bookings are kept.
+func DeleteAppointmentSlotTemplate(serviceID uint, templateID uint) error {
+	return database.DB.Where("service_id = ? AND template_id = ?", serviceID, templateID).
+		Delete(&AppointmentSlotTemplate{}).Error
+}
+
+// GetAppointmentSlotTemplates retrieves a service's slot templates
+func GetAppointmentSlotTemplates(serviceID uint) ([]AppointmentSlotTemplate, error) {
+	var templates []AppointmentSlotTemplate
+	err := database.DB.Where("service_id = ?", serviceID).Order("template_id ASC").Find(&templates).Error
+	return templates, err
+}
+
+// GetAppointmentSlotTemplateByID retrieves a slot template of a service by ID
+func GetAppointmentSlotTemplateByID(serviceID uint, templateID uint) (*AppointmentSlotTemplate, error) {
+	var template AppointmentSlotTemplate
+	err := database.DB.Where("service_id = ? AND template_id = ?", serviceID, templateID).First(&template).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("slot template not found")
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetAppointmentSlots lists a service's slots on a date (YYYY-MM-DD, in the venue's time zone)
+// with their remaining capacity. Slots that already started and closure days offer nothing.
+func GetAppointmentSlots(serviceID uint, date string) ([]AppointmentSlot, error) {
+	service, err := GetServiceByID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if service.VenueID == nil {
+		return nil, errors.New("service does not belong to a venue")
+	}
+	venue, err := GetVenueByID(*service.VenueID)
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := generateAppointmentSlots(database.DB, venue, service.ServiceID, date)
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 {
+		return slots, nil
+	}
+
+	var booked []Appointment
+	err = database.DB.Select("slot_start").
+		Where("service_id = ? AND status IN ? AND slot_start >= ? AND slot_start <= ?", service.ServiceID,
+			activeAppointmentStatuses, slots[0].Start, slots[len(slots)-1].Start).
+		Find(&booked).Error
+	if err != nil {
+		return nil, err
+	}
+	bookedAt := map[int64]int{}
+	for _, appointment := range booked {
+		bookedAt[appointment.SlotStart.Unix()]++
+	}
+
+	now := time.Now()
+	available := make([]AppointmentSlot, 0, len(slots))
+	for _, slot := range slots {
+		if !slot.Start.After(now) {
+			continue
+		}
+		slot.Booked = bookedAt[slot.Start.Unix()]
+		slot.Available = slot.Capacity - slot.Booked
+		if slot.Available < 0 {
+			slot.Available = 0
+		}
+		available = append(available, slot)
+	}
+	return available, nil
+}
+
+// generateAppointmentSlots lays out the slots of a service's active templates on a date,
+// keeping those within the venue's opening hours, in start order
+func generateAppointmentSlots(tx *gorm.DB, venue *Venue, serviceID uint, date string) ([]AppointmentSlot, error) {
+	day, err := time.ParseInLocation(dateLayout, date, venue.Location())
+	if err != nil {
+		return nil, errors.New("invalid date format, expected YYYY-MM-DD")
+	}
+
+	closed, err := venue.IsClosedOn(date)
+	if err != nil {
+		return nil, err
+	}
+	if closed {
+		return []AppointmentSlot{}, nil
+	}
+
+	var templates []AppointmentSlotTemplate
+	if err := tx.Where("service_id = ? AND active = ?", serviceID, true).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	slots := []AppointmentSlot{}
+	for _, template := range templates {
+		days, err := template.weekdays()
+		if err != nil {
+			return nil, err
+		}
+		if days != nil && !days[day.Weekday()] {
+			continue
+		}
+
+		start, end, ok := templatePeriod(&template, venue, day)
+		if !ok {
+			continue
+		}
+		length := time.Duration(template.SlotMinutes) * time.Minute
+		for slotStart := start; !slotStart.Add(length).After(end); slotStart = slotStart.Add(length) {
+			slotEnd := slotStart.Add(length)
+			// Slots must start and end within one opening period of the venue
+			closeAt, open := openingPeriodAt(venue.OpenTime, venue.CloseTime, slotStart)
+			if !open || (closeAt != nil && slotEnd.After(*closeAt)) {
+				continue
+			}
+			slots = append(slots, AppointmentSlot{
+				TemplateID: template.TemplateID,
+				Start:      slotStart,
+				End:        slotEnd,
+				Capacity:   template.Capacity,
+			})
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	return slots, nil
+}
+
+// templatePeriod returns when a template's slots start and end on a day, falling back to the venue's hours.
+// Periods past midnight end on the next day.
+func templatePeriod(template *AppointmentSlotTemplate, venue *Venue, day time.Time) (time.Time, time.Time, bool) {
+	startClock, endClock := template.StartTime, template.EndTime
+	if startClock == "" {
+		startClock = venue.OpenTime
+	}
+	if endClock == "" {
+		endClock = venue.CloseTime
+	}
+
+	startAt, err := time.Parse(clockLayout, startClock)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	endAt, err := time.Parse(clockLayout, endClock)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start := atClock(day, startAt)
+	end := atClock(day, endAt)
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, true
+}
+
+// findAppointmentSlot returns the slot of a service starting at the given time.
+// Slots past midnight belong to the previous day's templates, so both days are searched.
+func findAppointmentSlot(tx *gorm.DB, venue *Venue, serviceID uint, start time.Time) (*AppointmentSlot, error) {
+	local := start.In(venue.Location())
+	for _, day := range []time.Time{local, local.AddDate(0, 0, -1)} {
+		slots, err := generateAppointmentSlots(tx, venue, serviceID, day.Format(dateLayout))
+		if err != nil {
+			return nil, err
+		}
+		for _, slot := range slots {
+			if slot.Start.Equal(start) {
+				return &slot, nil
+			}
+		}
+	}
+	return nil, ErrSlotUnavailable
+}
@@ -0,0 +1,236 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Ticket priority classes
+const (
+	PriorityRegular         = "regular"
+	PriorityVIP             = "vip"
+	PriorityElderlyDisabled = "elderly_disabled"
+	PriorityAppointment     = "appointment"
+)
+
+// Service call policies
+const (
+	// CallPolicyStrict always calls the highest priority class first
+	CallPolicyStrict = "strict"
+	// CallPolicyWeighted calls one priority ticket after every Service.RegularPerPriority regular tickets
+	CallPolicyWeighted = "weighted"
+)
+
+// defaultRegularPerPriority is the weighted interleaving ratio used when a service doesn't set one
+const defaultRegularPerPriority = 3
+
+// priorityRankSQL ranks the priority classes, lowest first: appointment, elderly/disabled, VIP, regular.
+// Tickets transferred to the head of a queue rank before every class.
+const priorityRankSQL = "CASE WHEN at_head = TRUE THEN -1 ELSE CASE priority WHEN 'appointment' THEN 0 WHEN 'elderly_disabled' THEN 1 WHEN 'vip' THEN 2 ELSE 3 END END"
+
+// headRank is the rank of tickets transferred to the head of a queue
+const headRank = -1
+
+// priorityRanks mirrors priorityRankSQL
+var priorityRanks = map[string]int{
+	PriorityAppointment:     0,
+	PriorityElderlyDisabled: 1,
+	PriorityVIP:             2,
+	PriorityRegular:         3,
+}
+
+// ticketRank returns the rank of a ticket in priorityRankSQL
+func ticketRank(ticket *QueueTicket) int {
+	if ticket.AtHead {
+		return headRank
+	}
+	return priorityRanks[ticket.Priority]
+}
+
+// IsValidPriority reports whether priority is a known priority class
+func IsValidPriority(priority string) bool {
+	_, ok := priorityRanks[priority]
+	return ok
+}
+
+// kioskPriorities are the classes customers may choose themselves at an unattended kiosk.
+// VIP is granted by staff and appointments get theirs at check-in.
+var kioskPriorities = map[string]bool{
+	PriorityRegular:         true,
+	PriorityElderlyDisabled: true,
+}
+
+// IsKioskPriority reports whether priority can be chosen at a kiosk
+func IsKioskPriority(priority string) bool {
+	return kioskPriorities[priority]
+}
+
+// validateCallPolicy checks the service's call policy settings and fills in defaults
+func (s *Service) validateCallPolicy() error {
+	if s.CallPolicy == "" {
+		s.CallPolicy = CallPolicyStrict
+	}
+	if s.CallPolicy != CallPolicyStrict && s.CallPolicy != CallPolicyWeighted {
+		return errors.New("call_policy must be 'strict' or 'weighted'")
+	}
+	if s.RegularPerPriority == 0 {
+		s.RegularPerPriority = defaultRegularPerPriority
+	}
+	if s.RegularPerPriority < 0 {
+		return errors.New("regular_per_priority cannot be negative")
+	}
+	if s.PriorityAgingMinutes < 0 {
+		return errors.New("priority_aging_minutes cannot be negative")
+	}
+	if s.MaxBatchSize == 0 {
+		s.MaxBatchSize = 1
+	}
+	if s.MaxBatchSize < 0 {
+		return errors.New("max_batch_size cannot be negative")
+	}
+	return nil
+}
+
+// callOrdering orders waiting tickets by priority class, promoting tickets that waited
+// longer than the service's aging threshold ahead of every class
+type callOrdering struct {
+	agedBefore *time.Time // Tickets queued at or before this time are aged; nil disables aging
+}
+
+// newCallOrdering returns the call ordering of a service at the given time
+func newCallOrdering(service *Service, now time.Time) callOrdering {
+	var ordering callOrdering
+	if service.PriorityAgingMinutes > 0 {
+		agedBefore := now.Add(-time.Duration(service.PriorityAgingMinutes) * time.Minute)
+		ordering.agedBefore = &agedBefore
+	}
+	return ordering
+}
+
+// orderBy returns the ORDER BY clause calling tickets in this ordering
+func (o callOrdering) orderBy() clause.OrderBy {
+	expr := clause.Expr{SQL: priorityRankSQL + ", " + callOrder, WithoutParentheses: true}
+	if o.agedBefore != nil {
+		expr.SQL = "CASE WHEN queued_at <= ? THEN 0 ELSE 1 END, " + expr.SQL
+		expr.Vars = []interface{}{*o.agedBefore}
+	}
+	return clause.OrderBy{Expression: expr}
+}
+
+// before reports whether ticket a is called before ticket b in this ordering, mirroring orderBy
+// for tickets held in memory. Both tickets need a QueuedAt.
+func (o callOrdering) before(a *QueueTicket, b *QueueTicket) bool {
+	if o.agedBefore != nil {
+		agedA, agedB := !a.QueuedAt.After(*o.agedBefore), !b.QueuedAt.After(*o.agedBefore)
+		if agedA != agedB {
+			return agedA
+		}
+	}
+	if rankA, rankB := ticketRank(a), ticketRank(b); rankA != rankB {
+		return rankA < rankB
+	}
+	if !a.QueuedAt.Equal(*b.QueuedAt) {
+		return a.QueuedAt.Before(*b.QueuedAt)
+	}
+	return a.TicketID < b.TicketID
+}
+
+// whereAheadOf restricts a query to tickets called before the given ticket in this ordering
+func (o callOrdering) whereAheadOf(query *gorm.DB, ticket *QueueTicket) *gorm.DB {
+	rank := ticketRank(ticket)
+	sameRankAhead := query.Session(&gorm.Session{NewDB: true}).
+		Where(priorityRankSQL+" < ?", rank).
+		Or(priorityRankSQL+" = ? AND (queued_at < ? OR (queued_at = ? AND ticket_id < ?))", rank, ticket.QueuedAt, ticket.QueuedAt, ticket.TicketID)
+
+	if o.agedBefore == nil {
+		return query.Where(sameRankAhead)
+	}
+
+	if !ticket.QueuedAt.After(*o.agedBefore) {
+		// An aged ticket is only behind aged tickets, which keep their class order among themselves
+		return query.Where("queued_at <= ?", *o.agedBefore).Where(sameRankAhead)
+	}
+	return query.Where(
+		query.Session(&gorm.Session{NewDB: true}).
+			Where("queued_at <= ?", *o.agedBefore).
+			Or(sameRankAhead),
+	)
+}
+
+// selectNextTicket locks the ticket the counter should call next under the service's call policy
+func selectNextTicket(tx *gorm.DB, counter *Counter, service *Service) (*QueueTicket, error) {
+	waiting := func() *gorm.DB {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND service_id = ?", TicketStatusWaiting, service.ServiceID)
+		if counter.VenueID != nil {
+			query = query.Where("venue_id = ?", *counter.VenueID)
+		}
+		// Tickets transferred to a specific counter are only called there
+		query = query.Where("assigned_counter_id IS NULL OR assigned_counter_id = ?", counter.CounterID)
+		if counter.IsVIP && service.RestrictVIPCounters {
+			query = query.Where("priority = ?", PriorityVIP)
+		}
+		return query
+	}
+	ordering := newCallOrdering(service, time.Now())
+
+	var lanes []*gorm.DB
+	if service.CallPolicy == CallPolicyWeighted {
+		priorityTurn, err := isPriorityTurn(tx, counter, service)
+		if err != nil {
+			return nil, err
+		}
+		regular := waiting().Where("priority = ?", PriorityRegular)
+		priority := waiting().Where("priority <> ?", PriorityRegular)
+		if priorityTurn {
+			lanes = []*gorm.DB{priority, regular}
+		} else {
+			lanes = []*gorm.DB{regular, priority}
+		}
+	} else {
+		lanes = []*gorm.DB{waiting()}
+	}
+	// Tickets transferred to the head of the queue go first under either policy
+	lanes = append([]*gorm.DB{waiting().Where("at_head = ?", true)}, lanes...)
+
+	// Each lane falls back to the next when it has no waiting tickets.
+	// Take rather than First: First appends the primary key to ORDER BY and drops the expression.
+	for _, lane := range lanes {
+		var ticket QueueTicket
+		err := lane.Order(ordering.orderBy()).Take(&ticket).Error
+		if err == nil {
+			return &ticket, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrNoWaitingTickets
+}
+
+// isPriorityTurn reports whether the weighted policy calls a priority ticket next,
+// i.e. none of the last RegularPerPriority calls of the service was a priority ticket
+func isPriorityTurn(tx *gorm.DB, counter *Counter, service *Service) (bool, error) {
+	var recent []string
+	query := tx.Model(&QueueTicket{}).
+		Where("service_id = ? AND called_at IS NOT NULL", service.ServiceID)
+	if counter.VenueID != nil {
+		query = query.Where("venue_id = ?", *counter.VenueID)
+	}
+	err := query.Order("called_at DESC, ticket_id DESC").
+		Limit(service.RegularPerPriority).
+		Pluck("priority", &recent).Error
+	if err != nil {
+		return false, err
+	}
+
+	for _, priority := range recent {
+		if priority != PriorityRegular {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// orderingTicket is a waiting ticket for the call ordering tests, queued minutes after the start
+type orderingTicket struct {
+	priority string
+	queued   int
+	atHead   bool
+}
+
+// orderingTests share their tickets between the in-memory and the SQL ordering. Tickets are
+// listed in call order; with aging, tickets queued at or before minute 10 are aged at minute 30.
+var orderingTests = []struct {
+	name    string
+	aging   int // Service.PriorityAgingMinutes, evaluated at minute 30
+	tickets []orderingTicket
+}{
+	{
+		name: "classes before queue time",
+		tickets: []orderingTicket{
+			{PriorityAppointment, 20, false},
+			{PriorityElderlyDisabled, 5, false},
+			{PriorityElderlyDisabled, 15, false},
+			{PriorityVIP, 1, false},
+			{PriorityRegular, 0, false},
+			{PriorityRegular, 25, false},
+		},
+	},
+	{
+		name: "head placement before every class",
+		tickets: []orderingTicket{
+			{PriorityRegular, 28, true},
+			{PriorityAppointment, 0, false},
+			{PriorityRegular, 1, false},
+		},
+	},
+	{
+		name:  "aged tickets before every class, by class among themselves",
+		aging: 20,
+		tickets: []orderingTicket{
+			{PriorityVIP, 10, false},
+			{PriorityRegular, 2, false},
+			{PriorityRegular, 10, false},
+			{PriorityAppointment, 25, false},
+			{PriorityVIP, 11, false},
+			{PriorityRegular, 12, false},
+		},
+	},
+	{
+		name: "ties broken by ticket ID",
+		tickets: []orderingTicket{
+			{PriorityRegular, 5, false},
+			{PriorityRegular, 5, false},
+			{PriorityRegular, 5, false},
+		},
+	},
+}
+
+var orderingStart = time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+// orderingTickets builds the waiting tickets of a test, IDs following the listed order
+func orderingTickets(entries []orderingTicket) []QueueTicket {
+	tickets := make([]QueueTicket, len(entries))
+	for i, entry := range entries {
+		queuedAt := orderingStart.Add(time.Duration(entry.queued) * time.Minute)
+		tickets[i] = QueueTicket{
+			TicketID:    uint(i + 1),
+			UserID:      1,
+			QueueNumber: fmt.Sprintf("A-%03d", i+1),
+			Token:       fmt.Sprintf("token-%d", i+1),
+			Status:      TicketStatusWaiting,
+			Priority:    entry.priority,
+			QueuedAt:    &queuedAt,
+			AtHead:      entry.atHead,
+		}
+	}
+	return tickets
+}
+
+func TestCallOrderingBefore(t *testing.T) {
+	for _, tt := range orderingTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordering := newCallOrdering(&Service{PriorityAgingMinutes: tt.aging}, orderingStart.Add(30*time.Minute))
+			tickets := orderingTickets(tt.tickets)
+			for i := range tickets {
+				for j := range tickets {
+					if got, want := ordering.before(&tickets[i], &tickets[j]), i < j; got != want {
+						t.Errorf("before(ticket %d, ticket %d) = %v, want %v", i+1, j+1, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCallOrderingWhereAheadOf(t *testing.T) {
+	for _, tt := range orderingTests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			serviceID, venueID := uint(1), uint(1)
+			tickets := orderingTickets(tt.tickets)
+			for i := range tickets {
+				tickets[i].ServiceID, tickets[i].VenueID = &serviceID, &venueID
+			}
+			if err := db.Create(&tickets).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			service := &Service{PriorityAgingMinutes: tt.aging}
+			ordering := newCallOrdering(service, orderingStart.Add(30*time.Minute))
+			for i := range tickets {
+				var ahead []uint
+				query := db.Model(&QueueTicket{}).Where("status = ?", TicketStatusWaiting)
+				err := ordering.whereAheadOf(query, &tickets[i]).Order("ticket_id ASC").Pluck("ticket_id", &ahead).Error
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := i; len(ahead) != want {
+					t.Errorf("ticket %d has tickets %v ahead, want the first %d", i+1, ahead, want)
+					continue
+				}
+				for j, ticketID := range ahead {
+					if ticketID != uint(j+1) {
+						t.Errorf("ticket %d has tickets %v ahead, want the first %d", i+1, ahead, i)
+						break
+					}
+				}
+			}
+
+			// Calling in SQL order yields the listed order
+			var called []uint
+			err := db.Model(&QueueTicket{}).Order(ordering.orderBy()).Pluck("ticket_id", &called).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, ticketID := range called {
+				if ticketID != uint(i+1) {
+					t.Errorf("call order = %v, want the listed order", called)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestIsKioskPriority(t *testing.T) {
+	tests := []struct {
+		priority string
+		want     bool
+	}{
+		{PriorityRegular, true},
+		{PriorityElderlyDisabled, true},
+		{PriorityVIP, false},
+		{PriorityAppointment, false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsKioskPriority(tt.priority); got != tt.want {
+			t.Errorf("IsKioskPriority(%q) = %v, want %v", tt.priority, got, tt.want)
+		}
+	}
+}
@@ -87,7 +87,12 @@ func (c *Counter) CreateCounter(userID uint) error {
 	// Set the user ID
 	c.UserID = userID
 
-	return database.DB.Create(c).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(c).Error; err != nil {
+			return err
+		}
+		return ensurePrimaryCounterService(tx, c)
+	})
 }
 
 // GetCounterByID retrieves a counter by ID
@@ -116,17 +121,47 @@ func (c *Counter) UpdateCounter(userID uint, isAdmin bool) error {
 	if !isAdmin && c.UserID != userID {
 		return errors.New("unauthorized: cannot update this counter")
 	}
-	return database.DB.Save(c).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var previous Counter
+		if err := tx.First(&previous, c.CounterID).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(c).Error; err != nil {
+			return err
+		}
+
+		// A single-service counter moved to another service drops its old skill
+		if previous.ServiceID != nil && (c.ServiceID == nil || *c.ServiceID != *previous.ServiceID) {
+			var skills int64
+			if err := tx.Model(&CounterService{}).Where("counter_id = ?", c.CounterID).Count(&skills).Error; err != nil {
+				return err
+			}
+			if skills == 1 {
+				err := tx.Where("counter_id = ? AND service_id = ?", c.CounterID, *previous.ServiceID).
+					Delete(&CounterService{}).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return ensurePrimaryCounterService(tx, c)
+	})
 }
 
 // DeleteCounter deletes a counter by ID
 func DeleteCounter(id uint, userID uint, isAdmin bool) error {
-	query := database.DB.Where("counter_id = ?", id)
-	if !isAdmin {
-		query = query.Where("user_id = ?", userID)
-	}
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("counter_id = ?", id)
+		if !isAdmin {
+			query = query.Where("user_id = ?", userID)
+		}
 
-	return query.Delete(&Counter{}).Error
+		result := query.Delete(&Counter{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Where("counter_id = ?", id).Delete(&CounterService{}).Error
+	})
 }
 
 // GetCountersByVenue retrieves all counters belonging to a specific venue
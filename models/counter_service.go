@@ -0,0 +1,157 @@
+package models
+
+import (
+	"errors"
+	"queue-system-backend/database"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CounterService links a counter to a service it is skilled for
+type CounterService struct {
+	CounterID uint `json:"counter_id" gorm:"primaryKey;autoIncrement:false"`
+	ServiceID uint `json:"service_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Weight    int  `json:"weight" gorm:"not null;default:1"` // Multiplies the waiting time of the service's tickets when picking the next one
+}
+
+// TableName ensures GORM uses the correct table name
+func (CounterService) TableName() string {
+	return "CounterServices"
+}
+
+// GetCounterServices retrieves the services a counter is skilled for.
+// Counters without explicit skills serve their Counter.ServiceID with weight 1.
+func GetCounterServices(counter *Counter) ([]CounterService, error) {
+	return counterSkills(database.DB, counter)
+}
+
+// SetCounterServices replaces the services a counter is skilled for.
+// The counter's ServiceID is kept as one of its skills so older clients keep working.
+func SetCounterServices(counter *Counter, skills []CounterService) error {
+	if len(skills) == 0 {
+		return errors.New("a counter needs at least one service")
+	}
+
+	seen := map[uint]bool{}
+	for i := range skills {
+		skill := &skills[i]
+		if seen[skill.ServiceID] {
+			return errors.New("duplicate service in counter skills")
+		}
+		seen[skill.ServiceID] = true
+
+		if skill.Weight == 0 {
+			skill.Weight = 1
+		}
+		if skill.Weight < 0 {
+			return errors.New("weight cannot be negative")
+		}
+
+		service, err := GetServiceByID(skill.ServiceID)
+		if err != nil {
+			return err
+		}
+		if service.UserID == nil || *service.UserID != counter.UserID {
+			return errors.New("unauthorized: service does not belong to the counter owner")
+		}
+		if counter.VenueID != nil && service.VenueID != nil && *service.VenueID != *counter.VenueID {
+			return errors.New("service does not belong to the counter's venue")
+		}
+		skill.CounterID = counter.CounterID
+	}
+
+	if counter.ServiceID == nil || !seen[*counter.ServiceID] {
+		counter.ServiceID = &skills[0].ServiceID
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("counter_id = ?", counter.CounterID).Delete(&CounterService{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&skills).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Counter{}).
+			Where("counter_id = ?", counter.CounterID).
+			Update("service_id", *counter.ServiceID).Error
+	})
+}
+
+// ensurePrimaryCounterService records the counter's ServiceID as one of its skills
+func ensurePrimaryCounterService(tx *gorm.DB, counter *Counter) error {
+	if counter.ServiceID == nil {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&CounterService{CounterID: counter.CounterID, ServiceID: *counter.ServiceID, Weight: 1}).Error
+}
+
+// counterSkills loads a counter's skills, falling back to its ServiceID
+func counterSkills(tx *gorm.DB, counter *Counter) ([]CounterService, error) {
+	var skills []CounterService
+	err := tx.Where("counter_id = ?", counter.CounterID).Order("service_id ASC").Find(&skills).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(skills) == 0 && counter.ServiceID != nil {
+		skills = []CounterService{{CounterID: counter.CounterID, ServiceID: *counter.ServiceID, Weight: 1}}
+	}
+	return skills, nil
+}
+
+// whereCounterServes restricts a counter query to counters skilled for the service
+func whereCounterServes(query *gorm.DB, serviceID uint) *gorm.DB {
+	return query.Where("service_id = ? OR counter_id IN (?)", serviceID,
+		database.DB.Model(&CounterService{}).Select("counter_id").Where("service_id = ?", serviceID))
+}
+
+// selectNextTicketForCounter picks the ticket a counter calls next across all its skills.
+// Each service proposes a ticket under its own call policy; the one with the longest
+// weighted waiting time wins.
+func selectNextTicketForCounter(tx *gorm.DB, counter *Counter) (*QueueTicket, error) {
+	skills, err := counterSkills(tx, counter)
+	if err != nil {
+		return nil, err
+	}
+	if len(skills) == 0 {
+		return nil, errors.New("counter has no service assigned")
+	}
+
+	now := time.Now()
+	var best *QueueTicket
+	var bestScore float64
+	for _, skill := range skills {
+		var service Service
+		if err := tx.First(&service, skill.ServiceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		candidate, err := selectNextTicket(tx, counter, &service)
+		if errors.Is(err, ErrNoWaitingTickets) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		waitingSince := candidate.CreatedAt
+		if candidate.QueuedAt != nil {
+			waitingSince = *candidate.QueuedAt
+		}
+		score := now.Sub(waitingSince).Seconds() * float64(skill.Weight)
+		if best == nil || score > bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoWaitingTickets
+	}
+	return best, nil
+}
@@ -0,0 +1,367 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"queue-system-backend/database"
+	"queue-system-backend/events"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Counter session statuses
+const (
+	SessionStatusOpen   = "open"
+	SessionStatusPaused = "paused"
+	SessionStatusClosed = "closed"
+)
+
+// Pause reason codes
+const (
+	PauseReasonBreak       = "break"
+	PauseReasonPrayer      = "prayer"
+	PauseReasonSystemIssue = "system_issue"
+	PauseReasonOther       = "other"
+)
+
+var pauseReasons = map[string]bool{
+	PauseReasonBreak:       true,
+	PauseReasonPrayer:      true,
+	PauseReasonSystemIssue: true,
+	PauseReasonOther:       true,
+}
+
+var (
+	// ErrCounterNotOpen is returned when a counter without an open, unpaused session calls a ticket
+	ErrCounterNotOpen = errors.New("counter is not open")
+	// ErrSessionAlreadyOpen is returned when opening a counter that already has an active session
+	ErrSessionAlreadyOpen = errors.New("counter already has an active session")
+	// ErrNoActiveSession is returned when pausing, resuming or closing a counter that isn't open
+	ErrNoActiveSession = errors.New("counter has no active session")
+)
+
+// CounterSession is an operator's shift at a counter, from opening to closing
+type CounterSession struct {
+	SessionID   uint       `json:"session_id" gorm:"primaryKey;autoIncrement"`
+	CounterID   uint       `json:"counter_id" gorm:"not null;index"`
+	VenueID     *uint      `json:"venue_id" gorm:"index"`
+	OperatorID  uint       `json:"operator_id" gorm:"not null;index"`
+	Status      string     `json:"status" gorm:"size:20;not null;index"` // open, paused or closed
+	PauseReason string     `json:"pause_reason" gorm:"size:20"`          // Reason of the current pause
+	OpenedAt    time.Time  `json:"opened_at" gorm:"not null"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	ClosedBy    string     `json:"closed_by" gorm:"size:20"` // "operator", or "schedule" when closed at the counter's CloseTime
+}
+
+// TableName ensures GORM uses the correct table name
+func (CounterSession) TableName() string {
+	return "CounterSessions"
+}
+
+// CounterSessionPause is one pause within a counter session
+type CounterSessionPause struct {
+	PauseID   uint       `json:"pause_id" gorm:"primaryKey;autoIncrement"`
+	SessionID uint       `json:"session_id" gorm:"not null;index"`
+	Reason    string     `json:"reason" gorm:"size:20;not null"`
+	Note      string     `json:"note" gorm:"size:255"`
+	StartedAt time.Time  `json:"started_at" gorm:"not null"`
+	EndedAt   *time.Time `json:"ended_at"`
+}
+
+// TableName ensures GORM uses the correct table name
+func (CounterSessionPause) TableName() string {
+	return "CounterSessionPauses"
+}
+
+// IsValidPauseReason reports whether reason is a known pause reason code
+func IsValidPauseReason(reason string) bool {
+	return pauseReasons[reason]
+}
+
+// OpenCounterSession opens the counter mapped to the operator in User_Counter_Map
+func OpenCounterSession(operatorID uint) (*CounterSession, error) {
+	counterID, err := GetCounterIDByUserID(int(operatorID))
+	if err != nil {
+		return nil, errors.New("no counter is mapped to this user")
+	}
+
+	var session CounterSession
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var counter Counter
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&counter, counterID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("counter not found")
+			}
+			return err
+		}
+
+		var active int64
+		err = tx.Model(&CounterSession{}).
+			Where("counter_id = ? AND status <> ?", counter.CounterID, SessionStatusClosed).
+			Count(&active).Error
+		if err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrSessionAlreadyOpen
+		}
+
+		session = CounterSession{
+			CounterID:  counter.CounterID,
+			VenueID:    counter.VenueID,
+			OperatorID: operatorID,
+			Status:     SessionStatusOpen,
+			OpenedAt:   time.Now(),
+		}
+		return tx.Create(&session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishCounterSession(&session)
+	return &session, nil
+}
+
+// PauseCounterSession pauses the operator's counter with a reason code
+func PauseCounterSession(operatorID uint, reason string, note string) (*CounterSession, error) {
+	if !IsValidPauseReason(reason) {
+		return nil, errors.New("invalid pause reason")
+	}
+
+	return updateCounterSession(operatorID, func(tx *gorm.DB, session *CounterSession, now time.Time) error {
+		if session.Status != SessionStatusOpen {
+			return errors.New("counter is already paused")
+		}
+
+		pause := CounterSessionPause{SessionID: session.SessionID, Reason: reason, Note: note, StartedAt: now}
+		if err := tx.Create(&pause).Error; err != nil {
+			return err
+		}
+
+		session.Status = SessionStatusPaused
+		session.PauseReason = reason
+		return nil
+	})
+}
+
+// ResumeCounterSession ends the current pause of the operator's counter
+func ResumeCounterSession(operatorID uint) (*CounterSession, error) {
+	return updateCounterSession(operatorID, func(tx *gorm.DB, session *CounterSession, now time.Time) error {
+		if session.Status != SessionStatusPaused {
+			return errors.New("counter is not paused")
+		}
+		if err := endCounterPause(tx, session.SessionID, now); err != nil {
+			return err
+		}
+
+		session.Status = SessionStatusOpen
+		session.PauseReason = ""
+		return nil
+	})
+}
+
+// CloseCounterSession closes the operator's counter, ending any pause in progress
+func CloseCounterSession(operatorID uint) (*CounterSession, error) {
+	return updateCounterSession(operatorID, func(tx *gorm.DB, session *CounterSession, now time.Time) error {
+		return closeCounterSession(tx, session, now, "operator")
+	})
+}
+
+// GetActiveCounterSession retrieves the open or paused session of a counter
+func GetActiveCounterSession(counterID uint) (*CounterSession, error) {
+	var session CounterSession
+	err := database.DB.Where("counter_id = ? AND status <> ?", counterID, SessionStatusClosed).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoActiveSession
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetCounterSessions retrieves the sessions of a counter that overlap the given period, newest first
+func GetCounterSessions(counterID uint, from time.Time, to time.Time) ([]CounterSession, error) {
+	var sessions []CounterSession
+	err := database.DB.
+		Where("counter_id = ? AND opened_at < ? AND (closed_at IS NULL OR closed_at > ?)", counterID, to, from).
+		Order("opened_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// GetCounterSessionPauses retrieves the pauses of a session in order
+func GetCounterSessionPauses(sessionID uint) ([]CounterSessionPause, error) {
+	var pauses []CounterSessionPause
+	err := database.DB.Where("session_id = ?", sessionID).Order("started_at ASC").Find(&pauses).Error
+	return pauses, err
+}
+
+// updateCounterSession locks the active session of the operator's counter, applies change and saves it
+func updateCounterSession(operatorID uint, change func(tx *gorm.DB, session *CounterSession, now time.Time) error) (*CounterSession, error) {
+	counterID, err := GetCounterIDByUserID(int(operatorID))
+	if err != nil {
+		return nil, errors.New("no counter is mapped to this user")
+	}
+
+	var session CounterSession
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("counter_id = ? AND status <> ?", counterID, SessionStatusClosed).
+			First(&session).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoActiveSession
+			}
+			return err
+		}
+
+		if err := change(tx, &session, time.Now()); err != nil {
+			return err
+		}
+		return tx.Save(&session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishCounterSession(&session)
+	return &session, nil
+}
+
+// CloseExpiredCounterSessions closes the active sessions whose counter reached its CloseTime
+// (or the venue's, when the counter has none) in the venue's time zone since the session was opened.
+// Sessions are closed as of the closing time and the number closed is returned.
+func CloseExpiredCounterSessions(now time.Time) (int, error) {
+	var sessions []CounterSession
+	if err := database.DB.Where("status <> ?", SessionStatusClosed).Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, session := range sessions {
+		var counter Counter
+		if err := database.DB.First(&counter, session.CounterID).Error; err != nil {
+			continue
+		}
+		// Closing times are wall-clock times in the venue's time zone
+		closeTime := counter.CloseTime
+		openedAt := session.OpenedAt
+		if counter.VenueID != nil {
+			if venue, err := GetVenueByID(*counter.VenueID); err == nil {
+				if closeTime == "" {
+					closeTime = venue.CloseTime
+				}
+				openedAt = openedAt.In(venue.Location())
+			}
+		}
+		closeAt, ok := nextClosingTime(closeTime, openedAt)
+		if !ok || now.Before(closeAt) {
+			continue
+		}
+
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("session_id = ? AND status <> ?", session.SessionID, SessionStatusClosed).
+				First(&session).Error
+			if err != nil {
+				return err
+			}
+			if err := closeCounterSession(tx, &session, closeAt, "schedule"); err != nil {
+				return err
+			}
+			return tx.Save(&session).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Closed by the operator in the meantime
+			continue
+		}
+		if err != nil {
+			return closed, err
+		}
+
+		publishCounterSession(&session)
+		closed++
+	}
+	return closed, nil
+}
+
+// RunCounterAutoClose closes expired counter sessions every interval. It blocks, so run it in its own goroutine.
+func RunCounterAutoClose(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if closed, err := CloseExpiredCounterSessions(time.Now()); err != nil {
+			log.Printf("🔴 Failed to close counters at their closing time: %v", err)
+		} else if closed > 0 {
+			log.Printf("🟢 Closed %d counter(s) at their closing time", closed)
+		}
+	}
+}
+
+// closeCounterSession marks a locked session closed at the given time
+func closeCounterSession(tx *gorm.DB, session *CounterSession, at time.Time, closedBy string) error {
+	if session.Status == SessionStatusPaused {
+		if err := endCounterPause(tx, session.SessionID, at); err != nil {
+			return err
+		}
+	}
+
+	session.Status = SessionStatusClosed
+	session.PauseReason = ""
+	session.ClosedAt = &at
+	session.ClosedBy = closedBy
+	return nil
+}
+
+// endCounterPause ends the pause in progress of a session
+func endCounterPause(tx *gorm.DB, sessionID uint, at time.Time) error {
+	return tx.Model(&CounterSessionPause{}).
+		Where("session_id = ? AND ended_at IS NULL", sessionID).
+		Update("ended_at", at).Error
+}
+
+// isCounterOpen reports whether the counter has an open session that isn't paused
+func isCounterOpen(tx *gorm.DB, counterID uint) (bool, error) {
+	var open int64
+	err := tx.Model(&CounterSession{}).
+		Where("counter_id = ? AND status = ?", counterID, SessionStatusOpen).
+		Count(&open).Error
+	return open > 0, err
+}
+
+// whereCounterOpen restricts a counter query to counters with an open session that isn't paused
+func whereCounterOpen(query *gorm.DB) *gorm.DB {
+	return query.Where("counter_id IN (?)",
+		database.DB.Model(&CounterSession{}).Select("counter_id").Where("status = ?", SessionStatusOpen))
+}
+
+// CounterSessionEventData is the public part of a counter session carried by queue events
+type CounterSessionEventData struct {
+	CounterID   uint   `json:"counter_id"`
+	Status      string `json:"status"`
+	PauseReason string `json:"pause_reason"`
+}
+
+// publishCounterSession notifies displays and consoles that a counter opened, paused, resumed or closed
+func publishCounterSession(session *CounterSession) {
+	event := events.Event{
+		Type:      events.TypeCounterStatus,
+		CounterID: session.CounterID,
+		Data: CounterSessionEventData{
+			CounterID:   session.CounterID,
+			Status:      session.Status,
+			PauseReason: session.PauseReason,
+		},
+	}
+	if session.VenueID != nil {
+		event.VenueID = *session.VenueID
+	}
+	events.Publish(event)
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"queue-system-backend/database"
+	"time"
+)
+
+// CounterUtilization summarises how a counter was staffed over a period
+type CounterUtilization struct {
+	CounterID      uint               `json:"counter_id"`
+	Sessions       int                `json:"sessions"`
+	OpenMinutes    float64            `json:"open_minutes"`     // Time with a session, paused or not
+	PausedMinutes  float64            `json:"paused_minutes"`   // Time paused
+	ActiveMinutes  float64            `json:"active_minutes"`   // Open and not paused
+	Utilization    float64            `json:"utilization"`      // Active share of the open time, 0 to 1
+	PausesByReason map[string]float64 `json:"pauses_by_reason"` // Paused minutes per reason code
+}
+
+// GetCounterUtilization computes open, paused and active time per counter from the session log.
+// Sessions and pauses are clipped to the period; those still running count until now.
+func GetCounterUtilization(filter StatisticsFilter, from time.Time, to time.Time) ([]CounterUtilization, error) {
+	now := time.Now()
+	if to.After(now) {
+		to = now
+	}
+
+	query := database.DB.Where("opened_at < ? AND (closed_at IS NULL OR closed_at > ?)", to, from)
+	if filter.VenueID != 0 {
+		query = query.Where("venue_id = ?", filter.VenueID)
+	}
+	if filter.CounterID != 0 {
+		query = query.Where("counter_id = ?", filter.CounterID)
+	}
+
+	var sessions []CounterSession
+	if err := query.Order("counter_id ASC, opened_at ASC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return []CounterUtilization{}, nil
+	}
+
+	sessionIDs := make([]uint, len(sessions))
+	for i, session := range sessions {
+		sessionIDs[i] = session.SessionID
+	}
+	var pauses []CounterSessionPause
+	if err := database.DB.Where("session_id IN ?", sessionIDs).Find(&pauses).Error; err != nil {
+		return nil, err
+	}
+	pausesBySession := map[uint][]CounterSessionPause{}
+	for _, pause := range pauses {
+		pausesBySession[pause.SessionID] = append(pausesBySession[pause.SessionID], pause)
+	}
+
+	var results []CounterUtilization
+	byCounter := map[uint]int{}
+	for _, session := range sessions {
+		index, ok := byCounter[session.CounterID]
+		if !ok {
+			results = append(results, CounterUtilization{CounterID: session.CounterID, PausesByReason: map[string]float64{}})
+			index = len(results) - 1
+			byCounter[session.CounterID] = index
+		}
+		result := &results[index]
+
+		result.Sessions++
+		result.OpenMinutes += overlapMinutes(session.OpenedAt, session.ClosedAt, from, to)
+		for _, pause := range pausesBySession[session.SessionID] {
+			minutes := overlapMinutes(pause.StartedAt, pause.EndedAt, from, to)
+			result.PausedMinutes += minutes
+			result.PausesByReason[pause.Reason] += minutes
+		}
+	}
+
+	for i := range results {
+		result := &results[i]
+		result.ActiveMinutes = result.OpenMinutes - result.PausedMinutes
+		if result.OpenMinutes > 0 {
+			result.Utilization = result.ActiveMinutes / result.OpenMinutes
+		}
+	}
+	return results, nil
+}
+
+// overlapMinutes returns the minutes an interval, still running when end is nil, overlaps [from, to]
+func overlapMinutes(start time.Time, end *time.Time, from time.Time, to time.Time) float64 {
+	stop := to
+	if end != nil && end.Before(to) {
+		stop = *end
+	}
+	if start.Before(from) {
+		start = from
+	}
+	if !stop.After(start) {
+		return 0
+	}
+	return stop.Sub(start).Minutes()
+}
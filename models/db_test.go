@@ -0,0 +1,52 @@
+package models
+
+import (
+	"queue-system-backend/database"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// openTestDB points database.DB at a fresh in-memory SQLite database with the full schema
+// for the rest of the test
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+
+	// SQLite has no enum type; the ticket status is stored as plain text
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&QueueTicket{}); err != nil {
+		t.Fatal(err)
+	}
+	status := stmt.Schema.LookUpField("Status")
+	status.DataType = schema.String
+	status.Size = 20
+
+	// The tables of the initial schema are created outside the application
+	if err := db.AutoMigrate(&User{}, &Venue{}, &Service{}, &Counter{}, &QueueTicket{}, &QueueDisplay{}, &UserCounterMap{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
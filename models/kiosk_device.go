@@ -0,0 +1,238 @@
+package models
+
+import (
+	"errors"
+	"queue-system-backend/database"
+	"queue-system-backend/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// kioskPairingTTL is how long a pairing code stays valid
+const kioskPairingTTL = 15 * time.Minute
+
+// ErrKioskLimitReached is returned when a kiosk has issued its daily number of tickets
+var ErrKioskLimitReached = errors.New("kiosk daily ticket limit reached")
+
+// ErrKioskPriority is returned when a kiosk asks for a priority class reserved to staff
+var ErrKioskPriority = errors.New("priority is not available at kiosks")
+
+// KioskDevice is a self-service kiosk registered to a venue
+type KioskDevice struct {
+	KioskID          uint       `json:"kiosk_id" gorm:"primaryKey;autoIncrement"`
+	VenueID          uint       `json:"venue_id" gorm:"not null;index"`          // Foreign key to Venues table
+	UserID           uint       `json:"user_id" gorm:"not null"`                 // Owner of the venue
+	DeviceName       string     `json:"device_name" gorm:"size:100;not null"`    // Label shown to admins
+	APIKeyHash       *string    `json:"-" gorm:"size:64;uniqueIndex"`            // SHA-256 of the device API key
+	PairingCode      *string    `json:"pairing_code" gorm:"size:12;uniqueIndex"` // One-time code to obtain the API key
+	PairingExpiresAt *time.Time `json:"pairing_expires_at"`
+	DailyTicketLimit int        `json:"daily_ticket_limit" gorm:"default:0"` // 0 means unlimited
+	IsActive         bool       `json:"is_active" gorm:"default:1"`
+	LastSeenAt       *time.Time `json:"last_seen_at"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName ensures GORM uses the correct table name
+func (KioskDevice) TableName() string {
+	return "KioskDevices"
+}
+
+// CreateKioskDevice registers a kiosk and issues its first pairing code
+func CreateKioskDevice(device *KioskDevice) error {
+	if device.DeviceName == "" {
+		return errors.New("device_name is required")
+	}
+	if device.DailyTicketLimit < 0 {
+		return errors.New("daily_ticket_limit cannot be negative")
+	}
+	if err := device.newPairingCode(); err != nil {
+		return err
+	}
+	device.IsActive = true
+	return database.DB.Create(device).Error
+}
+
+// GetKioskDeviceByID retrieves a kiosk by ID
+func GetKioskDeviceByID(id uint) (*KioskDevice, error) {
+	var device KioskDevice
+	if err := database.DB.First(&device, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("kiosk not found")
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// GetKioskDevicesByUser retrieves the kiosks owned by a user
+func GetKioskDevicesByUser(userID uint) ([]KioskDevice, error) {
+	var devices []KioskDevice
+	if err := database.DB.Where("user_id = ?", userID).Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// UpdateKioskDevice saves changes to a kiosk
+func UpdateKioskDevice(device *KioskDevice) error {
+	if device.DeviceName == "" {
+		return errors.New("device_name is required")
+	}
+	if device.DailyTicketLimit < 0 {
+		return errors.New("daily_ticket_limit cannot be negative")
+	}
+	return database.DB.Save(device).Error
+}
+
+// DeleteKioskDevice deletes a kiosk by ID
+func DeleteKioskDevice(id uint) error {
+	return database.DB.Delete(&KioskDevice{}, id).Error
+}
+
+// ResetKioskPairing revokes the kiosk's API key and issues a new pairing code
+func ResetKioskPairing(device *KioskDevice) error {
+	if err := device.newPairingCode(); err != nil {
+		return err
+	}
+	device.APIKeyHash = nil
+	return database.DB.Save(device).Error
+}
+
+// PairKioskDevice exchanges a valid pairing code for a new API key.
+// The plain key is only returned here; the database keeps its hash.
+func PairKioskDevice(code string) (*KioskDevice, string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	var device KioskDevice
+	err := database.DB.Where("pairing_code = ?", code).First(&device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New("invalid pairing code")
+		}
+		return nil, "", err
+	}
+	if device.PairingExpiresAt == nil || time.Now().After(*device.PairingExpiresAt) {
+		return nil, "", errors.New("pairing code has expired")
+	}
+
+	apiKey, err := utils.GenerateAPIKey(32)
+	if err != nil {
+		return nil, "", errors.New("failed to generate api key")
+	}
+	hash := utils.HashAPIKey(apiKey)
+
+	device.APIKeyHash = &hash
+	device.PairingCode = nil
+	device.PairingExpiresAt = nil
+	if err := database.DB.Save(&device).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &device, apiKey, nil
+}
+
+// GetKioskDeviceByAPIKey retrieves an active kiosk by its API key
+func GetKioskDeviceByAPIKey(apiKey string) (*KioskDevice, error) {
+	var device KioskDevice
+	err := database.DB.Where("api_key_hash = ? AND is_active = ?", utils.HashAPIKey(apiKey), true).
+		First(&device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("kiosk not found")
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// TouchKioskDevice records that the kiosk was just seen
+func TouchKioskDevice(kioskID uint) error {
+	return database.DB.Model(&KioskDevice{}).
+		Where("kiosk_id = ?", kioskID).
+		Update("last_seen_at", time.Now()).Error
+}
+
+// IssueKioskTicket creates a ticket from a kiosk for one of its venue's services,
+// enforcing the device's daily issuance limit
+func IssueKioskTicket(device *KioskDevice, ticket *QueueTicket) error {
+	if ticket.WorkflowID != nil {
+		ticket.VenueID = &device.VenueID
+		if err := startWorkflow(ticket); err != nil {
+			return err
+		}
+	}
+	if ticket.ServiceID == nil {
+		return errors.New("service_id is required")
+	}
+	if ticket.Priority == "" {
+		ticket.Priority = PriorityRegular
+	}
+	if !IsKioskPriority(ticket.Priority) {
+		return ErrKioskPriority
+	}
+
+	service, err := GetServiceByID(*ticket.ServiceID)
+	if err != nil {
+		return err
+	}
+	if service.VenueID == nil || *service.VenueID != device.VenueID || service.UserID == nil {
+		return errors.New("service is not available at this kiosk")
+	}
+
+	// Kiosk tickets belong to the service owner, like tickets issued from the dashboard
+	ticket.UserID = *service.UserID
+	ticket.VenueID = &device.VenueID
+	ticket.KioskID = &device.KioskID
+
+	if device.DailyTicketLimit == 0 {
+		return CreateQueueTicket(ticket)
+	}
+
+	venue, err := GetVenueByID(device.VenueID)
+	if err != nil {
+		return err
+	}
+
+	// The kiosk row is locked while counting so concurrent requests can't all pass the limit
+	return createQueueTicket(ticket, ticketIssue{guard: func(tx *gorm.DB) error {
+		var locked KioskDevice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, device.KioskID).Error; err != nil {
+			return err
+		}
+		if locked.DailyTicketLimit == 0 {
+			return nil
+		}
+		startOfDay, _, err := venue.BusinessDayRange(venue.BusinessDate(time.Now()))
+		if err != nil {
+			return err
+		}
+
+		var issued int64
+		err = tx.Model(&QueueTicket{}).
+			Where("kiosk_id = ? AND created_at >= ?", device.KioskID, startOfDay).
+			Count(&issued).Error
+		if err != nil {
+			return err
+		}
+		if issued >= int64(locked.DailyTicketLimit) {
+			return ErrKioskLimitReached
+		}
+		return nil
+	}})
+}
+
+// newPairingCode sets a fresh pairing code valid for kioskPairingTTL
+func (d *KioskDevice) newPairingCode() error {
+	code, err := utils.GenerateCode(8)
+	if err != nil {
+		return errors.New("failed to generate pairing code")
+	}
+	expiresAt := time.Now().Add(kioskPairingTTL)
+
+	d.PairingCode = &code
+	d.PairingExpiresAt = &expiresAt
+	return nil
+}
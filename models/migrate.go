@@ -0,0 +1,199 @@
+package models
+
+import (
+	"context"
+	"queue-system-backend/database"
+	"queue-system-backend/utils"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// addedColumns lists the columns added to tables of the initial schema. Existing rows of a
+// model with defaults get the value of the field in defaults when its column is added.
+var addedColumns = []struct {
+	model    interface{}
+	defaults interface{}
+	fields   []string
+}{
+	{&Service{}, serviceDefaults(), []string{"QueuePrefix", "NumberPadding", "MaxPostpones", "CallPolicy", "RegularPerPriority", "PriorityAgingMinutes", "RestrictVIPCounters", "NoShowGraceSeconds", "RejoinPolicy", "RemoteJoinEnabled", "RemoteMaxWaiting", "RemoteMaxPerPhone", "MaxBatchSize", "SLAWaitMinutes", "SLATargetPercent"}},
+	{&Venue{}, nil, []string{"DayResetTime", "LastTicketCutoffMinutes", "Timezone"}},
+	{&QueueTicket{}, nil, []string{"ServingAt", "RecalledAt", "CancelledAt", "KioskID", "QueuedAt", "PostponeCount", "CancelledBy", "Priority", "AssignedCounterID", "AtHead", "WorkflowID", "WorkflowStep", "RecallCount", "AnnouncedAt", "Remote", "CallBatchID", "JoinedAt", "CustomerRejoins"}},
+	{&QueueDisplay{}, nil, []string{"CurrentTickets"}},
+}
+
+// AutoMigrate creates the tables and columns introduced after the initial schema.
+// Existing tables only get their missing columns added so the original DDL is otherwise left untouched.
+func AutoMigrate() error {
+	if err := database.DB.AutoMigrate(
+		&QueueSequence{},
+		&QueueTicketEvent{},
+		&KioskDevice{},
+		&CounterService{},
+		&CounterSession{},
+		&CounterSessionPause{},
+		&VenueClosure{},
+		&TicketTransfer{},
+		&Workflow{},
+		&WorkflowStep{},
+		&AppointmentSlotTemplate{},
+		&Appointment{},
+		&TicketLeg{},
+	); err != nil {
+		return err
+	}
+
+	for _, added := range addedColumns {
+		if err := addMissingColumns(added.model, added.defaults, added.fields...); err != nil {
+			return err
+		}
+	}
+
+	// The status enum grows with the ticket lifecycle; it is only rewritten when it changed
+	if err := alterColumnIfChanged(&QueueTicket{}, "Status"); err != nil {
+		return err
+	}
+
+	// Tickets issued before the call order existed keep their issue order
+	if err := database.DB.Model(&QueueTicket{}).
+		Where("queued_at IS NULL").
+		Update("queued_at", gorm.Expr("created_at")).Error; err != nil {
+		return err
+	}
+
+	// Tickets called before announcements were tracked start their no-show grace period at the call
+	if err := database.DB.Model(&QueueTicket{}).
+		Where("announced_at IS NULL AND status IN ?", []string{TicketStatusCalled, TicketStatusRecalled}).
+		Update("announced_at", gorm.Expr("COALESCE(recalled_at, called_at)")).Error; err != nil {
+		return err
+	}
+
+	if err := migrateTicketTokens(); err != nil {
+		return err
+	}
+
+	// Counters created before skills existed serve their single service
+	if err := database.DB.Exec(
+		"INSERT INTO CounterServices (counter_id, service_id, weight) " +
+			"SELECT counter_id, service_id, 1 FROM Counters c WHERE service_id IS NOT NULL " +
+			"AND NOT EXISTS (SELECT 1 FROM CounterServices cs WHERE cs.counter_id = c.counter_id)",
+	).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// migrateTicketTokens replaces duplicate or empty legacy tokens and adds the unique token index
+func migrateTicketTokens() error {
+	migrator := database.DB.Migrator()
+	if migrator.HasIndex(&QueueTicket{}, "idx_queue_ticket_token") {
+		return nil
+	}
+
+	var duplicates []string
+	err := database.DB.Model(&QueueTicket{}).
+		Group("token").
+		Having("COUNT(*) > 1").
+		Pluck("token", &duplicates).Error
+	if err != nil {
+		return err
+	}
+
+	var tickets []QueueTicket
+	err = database.DB.Select("ticket_id", "token").
+		Where("token IN ? OR token = '' OR token IS NULL", append(duplicates, "")).
+		Order("ticket_id ASC").
+		Find(&tickets).Error
+	if err != nil {
+		return err
+	}
+
+	// The first ticket keeps a duplicated token; later ones get a fresh one
+	kept := map[string]bool{}
+	for _, ticket := range tickets {
+		if ticket.Token != "" && !kept[ticket.Token] {
+			kept[ticket.Token] = true
+			continue
+		}
+		token, err := utils.GenerateTicketToken()
+		if err != nil {
+			return err
+		}
+		if err := database.DB.Model(&QueueTicket{}).Where("ticket_id = ?", ticket.TicketID).Update("token", token).Error; err != nil {
+			return err
+		}
+	}
+
+	return migrator.CreateIndex(&QueueTicket{}, "idx_queue_ticket_token")
+}
+
+// serviceDefaults is the Service backfilled into services created before a column existed
+func serviceDefaults() *Service {
+	service := NewService()
+	return &service
+}
+
+// addMissingColumns adds the given struct fields as columns when they don't exist yet,
+// filling existing rows with the field's value in defaults unless defaults is nil
+func addMissingColumns(model interface{}, defaults interface{}, fields ...string) error {
+	migrator := database.DB.Migrator()
+	for _, field := range fields {
+		if migrator.HasColumn(model, field) {
+			continue
+		}
+		if err := migrator.AddColumn(model, field); err != nil {
+			return err
+		}
+		if defaults == nil {
+			continue
+		}
+
+		stmt := &gorm.Statement{DB: database.DB}
+		if err := stmt.Parse(defaults); err != nil {
+			return err
+		}
+		schemaField := stmt.Schema.LookUpField(field)
+		value, _ := schemaField.ValueOf(context.Background(), reflect.ValueOf(defaults))
+		err := database.DB.Session(&gorm.Session{AllowGlobalUpdate: true}).
+			Model(model).
+			Update(schemaField.DBName, value).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alterColumnIfChanged alters a column when its database type differs from the struct field's,
+// so large tables aren't rewritten on every start
+func alterColumnIfChanged(model interface{}, field string) error {
+	stmt := &gorm.Statement{DB: database.DB}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	schemaField := stmt.Schema.LookUpField(field)
+	if schemaField == nil {
+		return nil
+	}
+
+	migrator := database.DB.Migrator()
+	columnTypes, err := migrator.ColumnTypes(model)
+	if err != nil {
+		return err
+	}
+	normalize := func(value string) string {
+		return strings.ToLower(strings.ReplaceAll(value, " ", ""))
+	}
+	for _, columnType := range columnTypes {
+		if columnType.Name() != schemaField.DBName {
+			continue
+		}
+		if current, ok := columnType.ColumnType(); ok && normalize(current) == normalize(string(schemaField.DataType)) {
+			return nil
+		}
+		break
+	}
+	return migrator.AlterColumn(model, field)
+}
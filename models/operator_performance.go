@@ -0,0 +1,200 @@
+package models
+
+import (
+	"queue-system-backend/database"
+	"sort"
+	"time"
+)
+
+// OperatorPerformance summarises an operator's work over a period
+type OperatorPerformance struct {
+	OperatorID         uint     `json:"operator_id"`
+	Username           string   `json:"username"`
+	CounterIDs         []uint   `json:"counter_ids"` // Counters mapped to the operator
+	Served             int      `json:"served"`
+	Skipped            int      `json:"skipped"`
+	SkippedRatio       *float64 `json:"skipped_ratio"`        // Skipped share of served and skipped tickets, null without either
+	AvgHandlingMinutes *float64 `json:"avg_handling_minutes"` // Called→completed, null when nothing was served
+	IdleMinutes        float64  `json:"idle_minutes"`         // Between finishing a ticket and calling the next within a session, pauses excluded
+	AvgIdleMinutes     *float64 `json:"avg_idle_minutes"`     // Per gap between two calls, null without one
+	Pauses             int      `json:"pauses"`
+	PauseMinutes       float64  `json:"pause_minutes"`
+}
+
+// GetOperatorPerformance computes the performance of each of the given operators. Tickets are
+// attributed by their operator_id, or by the counter mapping for those called without one.
+// Sessions and pauses are clipped to the period; those still running count until now.
+func GetOperatorPerformance(operators []User, filter StatisticsFilter, from time.Time, to time.Time) ([]OperatorPerformance, error) {
+	now := time.Now()
+	if to.After(now) {
+		to = now
+	}
+
+	sort.Slice(operators, func(i, j int) bool { return operators[i].UserID < operators[j].UserID })
+	results := make([]OperatorPerformance, len(operators))
+	byOperator := map[uint]*OperatorPerformance{}
+	operatorIDs := make([]uint, len(operators))
+	for i, operator := range operators {
+		results[i] = OperatorPerformance{OperatorID: operator.UserID, Username: operator.Username, CounterIDs: []uint{}}
+		byOperator[operator.UserID] = &results[i]
+		operatorIDs[i] = operator.UserID
+	}
+	if len(operators) == 0 {
+		return results, nil
+	}
+
+	// A counter mapped to a single operator attributes its tickets without operator_id to them
+	var mappings []UserCounterMap
+	if err := database.DB.Where("user_id IN ?", operatorIDs).Order("counter_id ASC").Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+	counterOperator := map[uint]uint{}
+	ambiguous := map[uint]bool{}
+	for _, mapping := range mappings {
+		counterID, userID := uint(mapping.CounterID), uint(mapping.UserID)
+		byOperator[userID].CounterIDs = append(byOperator[userID].CounterIDs, counterID)
+		if existing, ok := counterOperator[counterID]; ok && existing != userID {
+			ambiguous[counterID] = true
+		}
+		counterOperator[counterID] = userID
+	}
+
+	// Only the operators' tickets are loaded: their own and the ones their counters called without one
+	filter.OperatorID = 0
+	filter.OperatorIDs = operatorIDs
+	filter.OperatorCounterIDs = []uint{}
+	for counterID := range counterOperator {
+		if !ambiguous[counterID] {
+			filter.OperatorCounterIDs = append(filter.OperatorCounterIDs, counterID)
+		}
+	}
+	tickets, err := loadSeriesTickets(filter, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	inPeriod := func(t *time.Time) bool {
+		return t != nil && !t.Before(from) && t.Before(to)
+	}
+
+	handling := map[uint][]float64{}
+	calls := map[uint][]*seriesTicket{}
+	for i := range tickets {
+		ticket := &tickets[i]
+		var operatorID uint
+		switch {
+		case ticket.OperatorID != nil:
+			operatorID = *ticket.OperatorID
+		case ticket.CounterID != nil && !ambiguous[*ticket.CounterID]:
+			operatorID = counterOperator[*ticket.CounterID]
+		}
+		result, ok := byOperator[operatorID]
+		if !ok {
+			continue
+		}
+
+		if inPeriod(ticket.CalledAt) {
+			calls[operatorID] = append(calls[operatorID], ticket)
+		}
+		if inPeriod(ticket.CompletedAt) {
+			result.Served++
+			if ticket.CalledAt != nil {
+				handling[operatorID] = append(handling[operatorID], ticket.CompletedAt.Sub(*ticket.CalledAt).Minutes())
+			}
+		}
+		if inPeriod(ticket.SkippedAt) {
+			result.Skipped++
+		}
+	}
+
+	query := database.DB.Where("operator_id IN ? AND opened_at < ? AND (closed_at IS NULL OR closed_at > ?)", operatorIDs, to, from)
+	if filter.VenueID != 0 {
+		query = query.Where("venue_id = ?", filter.VenueID)
+	}
+	if filter.CounterID != 0 {
+		query = query.Where("counter_id = ?", filter.CounterID)
+	}
+	var sessions []CounterSession
+	if err := query.Order("opened_at ASC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	pausesBySession := map[uint][]CounterSessionPause{}
+	if len(sessions) > 0 {
+		sessionIDs := make([]uint, len(sessions))
+		for i, session := range sessions {
+			sessionIDs[i] = session.SessionID
+		}
+		var pauses []CounterSessionPause
+		if err := database.DB.Where("session_id IN ?", sessionIDs).Find(&pauses).Error; err != nil {
+			return nil, err
+		}
+		for _, pause := range pauses {
+			pausesBySession[pause.SessionID] = append(pausesBySession[pause.SessionID], pause)
+		}
+	}
+
+	sessionsByOperator := map[uint][]CounterSession{}
+	for _, session := range sessions {
+		sessionsByOperator[session.OperatorID] = append(sessionsByOperator[session.OperatorID], session)
+		result := byOperator[session.OperatorID]
+		for _, pause := range pausesBySession[session.SessionID] {
+			minutes := overlapMinutes(pause.StartedAt, pause.EndedAt, from, to)
+			if minutes > 0 {
+				result.Pauses++
+				result.PauseMinutes += minutes
+			}
+		}
+	}
+
+	for i := range results {
+		result := &results[i]
+		result.AvgHandlingMinutes = mean(handling[result.OperatorID])
+		if total := result.Served + result.Skipped; total > 0 {
+			ratio := float64(result.Skipped) / float64(total)
+			result.SkippedRatio = &ratio
+		}
+
+		idle := idleGaps(calls[result.OperatorID], sessionsByOperator[result.OperatorID], pausesBySession, from)
+		result.AvgIdleMinutes = mean(idle)
+		for _, gap := range idle {
+			result.IdleMinutes += gap
+		}
+	}
+	return results, nil
+}
+
+// idleGaps returns the minutes between an operator finishing each ticket and calling the next one,
+// leaving out gaps between sessions and the time paused. Batch calls have no gap.
+func idleGaps(calls []*seriesTicket, sessions []CounterSession, pausesBySession map[uint][]CounterSessionPause, from time.Time) []float64 {
+	sort.Slice(calls, func(i, j int) bool { return calls[i].CalledAt.Before(*calls[j].CalledAt) })
+
+	var gaps []float64
+	for i := 1; i < len(calls); i++ {
+		previous, next := calls[i-1], *calls[i].CalledAt
+		finished := previous.CompletedAt
+		if finished == nil {
+			finished = previous.SkippedAt
+		}
+		if finished == nil || finished.After(next) {
+			continue
+		}
+		start := *finished
+		if start.Before(from) {
+			start = from
+		}
+
+		for _, session := range sessions {
+			if session.OpenedAt.After(start) || (session.ClosedAt != nil && session.ClosedAt.Before(next)) {
+				continue
+			}
+			gap := next.Sub(start).Minutes()
+			for _, pause := range pausesBySession[session.SessionID] {
+				gap -= overlapMinutes(pause.StartedAt, pause.EndedAt, start, next)
+			}
+			gaps = append(gaps, gap)
+			break
+		}
+	}
+	return gaps
+}
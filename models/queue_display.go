@@ -5,19 +5,26 @@ import (
 	"errors"
 	"fmt"
 	"queue-system-backend/database"
+	"queue-system-backend/events"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // QueueDisplay Model
 type QueueDisplay struct {
-	DisplayID     uint      `json:"display_id" gorm:"primaryKey;autoIncrement"`
-	VenueID       uint      `json:"venue_id" gorm:"not null"`      // Foreign key to Venues table
-	UserID        uint      `json:"user_id" gorm:"not null"`       // Foreign key to Users table
-	ServiceID     uint      `json:"service_id" gorm:"not null"`    // Foreign key to Services table
-	CounterID     uint      `json:"counter_id" gorm:"not null"`    // Foreign key to Counters table
-	CurrentTicket string    `json:"current_ticket" gorm:"size:10"` // Current ticket being served
-	NextTickets   string    `json:"next_tickets" gorm:"type:json"` // Stored as JSON string
-	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	DisplayID     uint   `json:"display_id" gorm:"primaryKey;autoIncrement"`
+	VenueID       uint   `json:"venue_id" gorm:"not null"`      // Foreign key to Venues table
+	UserID        uint   `json:"user_id" gorm:"not null"`       // Foreign key to Users table
+	ServiceID     uint   `json:"service_id" gorm:"not null"`    // Foreign key to Services table
+	CounterID     uint   `json:"counter_id" gorm:"not null"`    // Foreign key to Counters table
+	CurrentTicket string `json:"current_ticket" gorm:"size:10"` // Current ticket being served
+	NextTickets   string `json:"next_tickets" gorm:"type:json"` // Stored as JSON string
+
+	// Every ticket of the counter's last call as a JSON array; several after a batch call
+	CurrentTickets string    `json:"current_tickets" gorm:"type:json"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // TableName ensures GORM uses the correct table name
@@ -25,9 +32,24 @@ func (QueueDisplay) TableName() string {
 	return "QueueDisplay"
 }
 
+// defaultTicketLists fills in empty ticket lists, which a json column doesn't accept
+func (d *QueueDisplay) defaultTicketLists() {
+	if d.NextTickets == "" {
+		d.NextTickets = "[]"
+	}
+	if d.CurrentTickets == "" {
+		d.CurrentTickets = "[]"
+	}
+}
+
 // CreateQueueDisplay creates a new display entry
 func CreateQueueDisplay(display *QueueDisplay) error {
-	return database.DB.Create(display).Error
+	display.defaultTicketLists()
+	if err := database.DB.Create(display).Error; err != nil {
+		return err
+	}
+	publishDisplayEvent(display)
+	return nil
 }
 
 // GetQueueDisplayByCounterID retrieves display data by CounterID
@@ -41,35 +63,158 @@ func GetQueueDisplayByCounterID(counterID uint) (*QueueDisplay, error) {
 
 // UpdateQueueDisplay updates display details
 func UpdateQueueDisplay(display *QueueDisplay) error {
-	return database.DB.Save(display).Error
+	display.defaultTicketLists()
+	if err := database.DB.Save(display).Error; err != nil {
+		return err
+	}
+	publishDisplayEvent(display)
+	return nil
+}
+
+// DisplayEventData is the public part of a queue display carried by queue events
+type DisplayEventData struct {
+	CounterID      uint      `json:"counter_id"`
+	ServiceID      uint      `json:"service_id"`
+	CurrentTicket  string    `json:"current_ticket"`
+	CurrentTickets string    `json:"current_tickets"`
+	NextTickets    string    `json:"next_tickets"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// publishDisplayEvent notifies display screens that a QueueDisplay row changed
+func publishDisplayEvent(display *QueueDisplay) {
+	events.Publish(events.Event{
+		Type:      events.TypeDisplayUpdated,
+		VenueID:   display.VenueID,
+		ServiceID: display.ServiceID,
+		CounterID: display.CounterID,
+		Data: DisplayEventData{
+			CounterID:      display.CounterID,
+			ServiceID:      display.ServiceID,
+			CurrentTicket:  display.CurrentTicket,
+			CurrentTickets: display.CurrentTickets,
+			NextTickets:    display.NextTickets,
+			UpdatedAt:      display.UpdatedAt,
+		},
+	})
 }
 
-// AutoAssignNextTicket updates `CurrentTicket` and removes it from `NextTickets`
-func (qd *QueueDisplay) AutoAssignNextTicket() error {
-	var nextTickets []string
-	if err := json.Unmarshal([]byte(qd.NextTickets), &nextTickets); err != nil {
-		return errors.New("failed to parse next_tickets")
+// nextTicketsPreviewSize is the number of upcoming tickets shown in `NextTickets`
+const nextTicketsPreviewSize = 5
+
+// syncQueueDisplay sets the counter's `CurrentTicket` to the first of the called tickets, lists them all
+// in `CurrentTickets` and refreshes `NextTickets` on every display of the same venue and service,
+// creating the counter's display if needed
+func syncQueueDisplay(tx *gorm.DB, counter *Counter, serviceID uint, venueID uint, currentTickets []string) (*QueueDisplay, error) {
+	var display QueueDisplay
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("counter_id = ?", counter.CounterID).
+		First(&display).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		display = QueueDisplay{
+			VenueID:     venueID,
+			UserID:      counter.UserID,
+			ServiceID:   serviceID,
+			CounterID:   counter.CounterID,
+			NextTickets: "[]",
+		}
 	}
 
-	if len(nextTickets) == 0 {
-		return errors.New("no next tickets available")
+	nextTickets, err := waitingQueueNumbers(tx, venueID, serviceID, nextTicketsPreviewSize)
+	if err != nil {
+		return nil, err
 	}
 
-	// Move the first ticket to CurrentTicket
-	qd.CurrentTicket = nextTickets[0]
+	current, err := json.Marshal(currentTickets)
+	if err != nil {
+		return nil, errors.New("failed to update current_tickets")
+	}
 
-	// Remove the first ticket from the list
-	nextTickets = nextTickets[1:]
+	// Counters serving several services show the queue of the ticket they called last
+	display.ServiceID = serviceID
+	display.CurrentTicket = currentTickets[0]
+	display.CurrentTickets = string(current)
+	display.NextTickets = nextTickets
+	if err := tx.Save(&display).Error; err != nil {
+		return nil, err
+	}
 
-	// Convert back to JSON string
-	updatedTickets, err := json.Marshal(nextTickets)
+	// Keep the upcoming list consistent on the other counters serving the same queue
+	err = tx.Model(&QueueDisplay{}).
+		Where("venue_id = ? AND service_id = ? AND counter_id <> ?", venueID, serviceID, counter.CounterID).
+		Update("next_tickets", nextTickets).Error
 	if err != nil {
-		return errors.New("failed to update next_tickets")
+		return nil, err
 	}
 
-	qd.NextTickets = string(updatedTickets)
+	return &display, nil
+}
 
-	return database.DB.Save(qd).Error
+// refreshNextTickets recomputes `NextTickets` on every display of a venue and service
+// after the waiting queue changed, and returns the updated displays
+func refreshNextTickets(tx *gorm.DB, venueID uint, serviceID uint) ([]QueueDisplay, error) {
+	nextTickets, err := waitingQueueNumbers(tx, venueID, serviceID, nextTicketsPreviewSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var displays []QueueDisplay
+	err = tx.Where("venue_id = ? AND service_id = ?", venueID, serviceID).Find(&displays).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(displays) == 0 {
+		return nil, nil
+	}
+
+	err = tx.Model(&QueueDisplay{}).
+		Where("venue_id = ? AND service_id = ?", venueID, serviceID).
+		Update("next_tickets", nextTickets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range displays {
+		displays[i].NextTickets = nextTickets
+	}
+	return displays, nil
+}
+
+// publishDisplayEvents publishes a display event for each display
+func publishDisplayEvents(displays []QueueDisplay) {
+	for i := range displays {
+		publishDisplayEvent(&displays[i])
+	}
+}
+
+// waitingQueueNumbers returns the next waiting queue numbers, in the service's call order, as a JSON array string
+func waitingQueueNumbers(tx *gorm.DB, venueID uint, serviceID uint, limit int) (string, error) {
+	var service Service
+	if err := tx.First(&service, serviceID).Error; err != nil {
+		return "", errors.New("service not found")
+	}
+
+	var numbers []string
+	err := tx.Model(&QueueTicket{}).
+		Where("status = ? AND venue_id = ? AND service_id = ?", TicketStatusWaiting, venueID, serviceID).
+		Order(newCallOrdering(&service, time.Now()).orderBy()).
+		Limit(limit).
+		Pluck("queue_number", &numbers).Error
+	if err != nil {
+		return "", err
+	}
+
+	if numbers == nil {
+		numbers = []string{}
+	}
+	encoded, err := json.Marshal(numbers)
+	if err != nil {
+		return "", errors.New("failed to update next_tickets")
+	}
+	return string(encoded), nil
 }
 
 // GetQueueDisplays retrieves QueueDisplay entries with optional filters
@@ -112,12 +257,20 @@ func GetNextCounter(venueID uint, serviceID uint) ([]QueueDisplay, error) {
 
 // ResetQueueDisplay clears all tickets on the display
 func ResetQueueDisplay() error {
-	return database.DB.Model(&QueueDisplay{}).
+	err := database.DB.Model(&QueueDisplay{}).
 		Where("1 = 1").
 		Updates(map[string]interface{}{
-			"current_ticket": "",
-			"next_tickets":   "[]",
+			"current_ticket":  "",
+			"current_tickets": "[]",
+			"next_tickets":    "[]",
 		}).Error
+	if err != nil {
+		return err
+	}
+
+	// Without a venue or service the event reaches every display
+	events.Publish(events.Event{Type: events.TypeDisplayUpdated})
+	return nil
 }
 
 // GetDisplayAnalytics retrieves analytics for a specific venue and service
@@ -127,22 +280,30 @@ type DisplayAnalytics struct {
 	TopCounter   string `json:"top_counter"`
 }
 
+// The date is the venue's business day, today by default, bounded in the venue's time zone
 func GetDisplayAnalytics(venueID, serviceID uint, date string) (*DisplayAnalytics, error) {
 	var analytics DisplayAnalytics
-	var dateFilter string
 
-	if date != "" {
-		parsedDate, _ := time.Parse("2006-01-02", date)
-		dateFilter = parsedDate.Format("2006-01-02")
-	} else {
-		dateFilter = time.Now().Format("2006-01-02")
+	venue := &Venue{}
+	if venueID != 0 {
+		var err error
+		if venue, err = GetVenueByID(venueID); err != nil {
+			return nil, err
+		}
+	}
+	if date == "" {
+		date = venue.BusinessDate(time.Now())
+	}
+	dayStart, dayEnd, err := venue.BusinessDayRange(date)
+	if err != nil {
+		return nil, err
 	}
 
 	query := database.DB.Table("QueueTickets").Select(
 		"COUNT(CASE WHEN status = 'completed' THEN 1 ELSE NULL END) AS total_called",
 		"COUNT(CASE WHEN status = 'waiting' THEN 1 ELSE NULL END) AS total_in_queue",
 		"MAX(counter_id) AS top_counter").
-		Where("DATE(created_at) = ?", dateFilter)
+		Where("created_at >= ? AND created_at < ?", dayStart, dayEnd)
 
 	if venueID != 0 {
 		query = query.Where("venue_id = ?", venueID)
@@ -151,7 +312,7 @@ func GetDisplayAnalytics(venueID, serviceID uint, date string) (*DisplayAnalytic
 		query = query.Where("service_id = ?", serviceID)
 	}
 
-	err := query.Scan(&analytics).Error
+	err = query.Scan(&analytics).Error
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +326,7 @@ func GetCurrentTicket(venueID, serviceID, counterID uint) (*QueueDisplay, error)
 
 	// Build the query
 	query := database.DB.Table("QueueDisplay").
-		Select("display_id, venue_id, user_id, service_id, counter_id, current_ticket, next_tickets, updated_at").
+		Select("display_id, venue_id, user_id, service_id, counter_id, current_ticket, current_tickets, next_tickets, updated_at").
 		Where("counter_id = ?", counterID)
 
 	// Optional filters for venue_id and service_id
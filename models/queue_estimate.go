@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"queue-system-backend/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// estimateWindow is the recent period used to measure service rates
+	estimateWindow = 2 * time.Hour
+	// estimateFallbackWindow is used when nothing was completed within estimateWindow
+	estimateFallbackWindow = 7 * 24 * time.Hour
+)
+
+// QueueEstimate tells a customer where they stand in the queue
+type QueueEstimate struct {
+	Position              int64    `json:"position"`                // 1-based position among waiting tickets, 0 when not waiting
+	TicketsAhead          int64    `json:"tickets_ahead"`           // Waiting tickets called before this one
+	OpenCounters          int64    `json:"open_counters"`           // Open, unpaused counters serving the service
+	AverageServiceMinutes float64  `json:"average_service_minutes"` // Recent average handling time per ticket
+	EstimatedWaitMinutes  *float64 `json:"estimated_wait_minutes"`  // Null when there is not enough data
+}
+
+// EstimateQueueTicketWait computes the ticket's position and estimated wait from
+// recent service rates and the number of open counters
+func EstimateQueueTicketWait(ticket *QueueTicket) (*QueueEstimate, error) {
+	estimate := &QueueEstimate{}
+	if ticket.Status != TicketStatusWaiting || ticket.VenueID == nil || ticket.ServiceID == nil || ticket.QueuedAt == nil {
+		return estimate, nil
+	}
+
+	service, err := GetServiceByID(*ticket.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Under the weighted policy this is the strict order, an upper bound for regular tickets
+	query := database.DB.Model(&QueueTicket{}).
+		Where("status = ? AND venue_id = ? AND service_id = ?", TicketStatusWaiting, *ticket.VenueID, *ticket.ServiceID)
+	err = newCallOrdering(service, time.Now()).whereAheadOf(query, ticket).Count(&estimate.TicketsAhead).Error
+	if err != nil {
+		return nil, err
+	}
+	estimate.Position = estimate.TicketsAhead + 1
+
+	estimate.OpenCounters, err = CountOpenCounters(*ticket.VenueID, *ticket.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate.AverageServiceMinutes, err = AverageServiceMinutes(*ticket.VenueID, *ticket.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if estimate.OpenCounters > 0 && estimate.AverageServiceMinutes > 0 {
+		wait := float64(estimate.TicketsAhead) * estimate.AverageServiceMinutes / float64(estimate.OpenCounters)
+		estimate.EstimatedWaitMinutes = &wait
+	}
+
+	return estimate, nil
+}
+
+// CountOpenCounters counts the counters skilled for a service that are open and not paused
+func CountOpenCounters(venueID uint, serviceID uint) (int64, error) {
+	var count int64
+	query := whereCounterOpen(database.DB.Model(&Counter{}).Where("venue_id = ?", venueID))
+	err := whereCounterServes(query, serviceID).Count(&count).Error
+	return count, err
+}
+
+// AverageServiceMinutes returns the average handling time (called to completed) of recently
+// completed tickets, falling back to a longer window when the recent one has no data
+func AverageServiceMinutes(venueID uint, serviceID uint) (float64, error) {
+	for _, window := range []time.Duration{estimateWindow, estimateFallbackWindow} {
+		query := database.DB.Model(&QueueTicket{}).
+			Where("status = ? AND venue_id = ? AND service_id = ?", TicketStatusCompleted, venueID, serviceID).
+			Where("called_at IS NOT NULL AND completed_at >= ?", time.Now().Add(-window))
+		average, err := averageMinutes(query, "called_at", "completed_at")
+		if err != nil {
+			return 0, err
+		}
+		if average != nil {
+			return *average, nil
+		}
+	}
+	return 0, nil
+}
+
+// averageMinutes returns the average minutes between two timestamp columns over the query's tickets,
+// or nil without any, as a single aggregate row
+func averageMinutes(query *gorm.DB, startColumn string, endColumn string) (*float64, error) {
+	var average sql.NullFloat64
+	err := query.Select("AVG(" + minutesBetweenSQL(query, startColumn, endColumn) + ")").Row().Scan(&average)
+	if err != nil {
+		return nil, err
+	}
+	if !average.Valid {
+		return nil, nil
+	}
+	return &average.Float64, nil
+}
+
+// minutesBetweenSQL returns the minutes from startColumn to endColumn as an SQL expression in db's dialect
+func minutesBetweenSQL(db *gorm.DB, startColumn string, endColumn string) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return fmt.Sprintf("TIMESTAMPDIFF(SECOND, %s, %s) / 60", startColumn, endColumn)
+	case "postgres":
+		return fmt.Sprintf("EXTRACT(EPOCH FROM (%s - %s)) / 60", endColumn, startColumn)
+	default:
+		return fmt.Sprintf("(julianday(%s) - julianday(%s)) * 1440", endColumn, startColumn)
+	}
+}
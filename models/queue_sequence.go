@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QueueSequence holds the last issued queue number per venue, service and business day
+type QueueSequence struct {
+	SequenceID   uint      `json:"sequence_id" gorm:"primaryKey;autoIncrement"`
+	VenueID      uint      `json:"venue_id" gorm:"not null;uniqueIndex:idx_queue_sequence_day"`
+	ServiceID    uint      `json:"service_id" gorm:"not null;uniqueIndex:idx_queue_sequence_day"`
+	BusinessDate string    `json:"business_date" gorm:"size:10;not null;uniqueIndex:idx_queue_sequence_day"` // YYYY-MM-DD
+	LastNumber   int       `json:"last_number" gorm:"not null;default:0"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName ensures GORM uses the correct table name
+func (QueueSequence) TableName() string {
+	return "QueueSequences"
+}
+
+// NextQueueSequence increments and returns the sequence for the given business day.
+// The upsert locks the sequence row until tx commits, so concurrent callers never share a number.
+func NextQueueSequence(tx *gorm.DB, venueID uint, serviceID uint, businessDate string) (int, error) {
+	sequence := QueueSequence{
+		VenueID:      venueID,
+		ServiceID:    serviceID,
+		BusinessDate: businessDate,
+		LastNumber:   1,
+	}
+
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "venue_id"}, {Name: "service_id"}, {Name: "business_date"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_number": gorm.Expr("last_number + 1"),
+			"updated_at":  time.Now(),
+		}),
+	}).Create(&sequence).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment queue sequence: %w", err)
+	}
+
+	var lastNumber int
+	err = tx.Model(&QueueSequence{}).
+		Where("venue_id = ? AND service_id = ? AND business_date = ?", venueID, serviceID, businessDate).
+		Pluck("last_number", &lastNumber).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to read queue sequence: %w", err)
+	}
+
+	return lastNumber, nil
+}
+
+// FormatQueueNumber renders a sequence number using the service prefix and zero-padding, e.g. "A-001"
+func FormatQueueNumber(prefix string, padding int, number int) string {
+	if padding < 0 {
+		padding = 0
+	}
+	formatted := fmt.Sprintf("%0*d", padding, number)
+
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return formatted
+	}
+	return prefix + "-" + formatted
+}
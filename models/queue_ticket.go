@@ -2,10 +2,14 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"queue-system-backend/database"
+	"queue-system-backend/events"
+	"queue-system-backend/utils"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type QueueTicket struct {
@@ -19,13 +23,47 @@ type QueueTicket struct {
 	CustomerPhone string     `json:"customer_phone" gorm:"size:20"`
 	PhotoURL      string     `json:"photo_url" gorm:"size:255"`
 	QueueNumber   string     `json:"queue_number" gorm:"size:10;not null"`
-	Token         string     `json:"token" gorm:"size:255"` // New field for token
-	Status        string     `json:"status" gorm:"type:enum('waiting','called','skipped','completed');default:waiting"`
+	Token         string     `json:"token" gorm:"size:255;uniqueIndex:idx_queue_ticket_token"` // Customer token, see utils.GenerateTicketToken
+	Status        string     `json:"status" gorm:"type:enum('waiting','called','serving','completed','skipped','recalled','cancelled');default:waiting"`
 	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	CalledAt      *time.Time `json:"called_at"`
+	ServingAt     *time.Time `json:"serving_at"`
 	CompletedAt   *time.Time `json:"completed_at"`
-	SkippedAt     *time.Time `json:"skipped_at"`  // New field for skipped timestamp
-	OperatorID    *uint      `json:"operator_id"` // New field for operator ID
+	SkippedAt     *time.Time `json:"skipped_at"` // New field for skipped timestamp
+	RecalledAt    *time.Time `json:"recalled_at"`
+	CancelledAt   *time.Time `json:"cancelled_at"`
+	OperatorID    *uint      `json:"operator_id"`            // New field for operator ID
+	KioskID       *uint      `json:"kiosk_id"`               // Kiosk that issued the ticket, if any
+	QueuedAt      *time.Time `json:"queued_at" gorm:"index"` // Position in the call order; moves when a ticket is postponed
+	PostponeCount int        `json:"postpone_count" gorm:"default:0"`
+	CancelledBy   string     `json:"cancelled_by" gorm:"size:20"`               // "customer" or "operator"
+	Priority      string     `json:"priority" gorm:"size:20;default:'regular'"` // Priority class, see PriorityRegular
+	// Counter the ticket was transferred to; only that counter calls it
+	AssignedCounterID *uint `json:"assigned_counter_id"`
+	// Set when a transfer placed the ticket at the head of its queue, ahead of every priority class
+	AtHead bool `json:"at_head" gorm:"default:false"`
+	// Workflow the ticket travels through and the position of its current step, see Workflow
+	WorkflowID   *uint `json:"workflow_id" gorm:"index"`
+	WorkflowStep int   `json:"workflow_step" gorm:"default:0"`
+	// Times the ticket was re-announced while called, and when it was last called or announced
+	RecallCount int        `json:"recall_count" gorm:"default:0"`
+	AnnouncedAt *time.Time `json:"announced_at"`
+	// Set when the customer took the ticket through the public remote join endpoint
+	Remote bool `json:"remote" gorm:"default:false"`
+	// Tickets called together in one batch call share the ID of the batch's first ticket
+	CallBatchID *uint `json:"call_batch_id" gorm:"index"`
+	// When the ticket joined its current service after a transfer or workflow step, see TicketLeg
+	JoinedAt *time.Time `json:"joined_at"`
+	// Times the customer put the skipped ticket back in the queue themselves, see RejoinQueueTicketByToken
+	CustomerRejoins int `json:"customer_rejoins" gorm:"default:0"`
+}
+
+// callOrder is the order in which waiting tickets are called
+const callOrder = "queued_at ASC, ticket_id ASC"
+
+// whereBehind restricts a query to tickets called after the given ticket
+func whereBehind(query *gorm.DB, ticket *QueueTicket) *gorm.DB {
+	return query.Where("queued_at > ? OR (queued_at = ? AND ticket_id > ?)", ticket.QueuedAt, ticket.QueuedAt, ticket.TicketID)
 }
 
 // TableName ensures GORM uses the correct table name
@@ -33,8 +71,53 @@ func (QueueTicket) TableName() string {
 	return "QueueTickets"
 }
 
-// CreateQueueTicket inserts a new ticket
+// applyStatusTimestamp mirrors the timestamp column stamped by a transition onto the struct
+func (t *QueueTicket) applyStatusTimestamp(status string, at time.Time) {
+	switch status {
+	case TicketStatusCalled:
+		t.CalledAt = &at
+	case TicketStatusServing:
+		t.ServingAt = &at
+	case TicketStatusCompleted:
+		t.CompletedAt = &at
+	case TicketStatusSkipped:
+		t.SkippedAt = &at
+	case TicketStatusRecalled:
+		t.RecalledAt = &at
+	case TicketStatusCancelled:
+		t.CancelledAt = &at
+	}
+}
+
+// maxTokenAttempts is how many tokens are tried before giving up on a ticket insert
+const maxTokenAttempts = 3
+
+// ticketIssue adjusts how createQueueTicket issues a ticket
+type ticketIssue struct {
+	// guard runs first in the insert transaction, e.g. to enforce an issuance limit behind a row lock
+	guard func(tx *gorm.DB) error
+	// issued runs last in the insert transaction, once the ticket has its ID, e.g. to link it to a booking
+	issued func(tx *gorm.DB) error
+	// booked tickets were scheduled ahead, so only closure days stop them, not the opening hours,
+	// the last ticket cutoff or the counter schedules
+	booked bool
+}
+
+// CreateQueueTicket inserts a new ticket, assigns its queue number from the
+// venue/service sequence of the current business day and generates its customer token.
+// Tickets for a workflow join the queue of its first step.
 func CreateQueueTicket(ticket *QueueTicket) error {
+	return createQueueTicket(ticket, ticketIssue{})
+}
+
+// createQueueTicket is CreateQueueTicket with issuance adjustments
+func createQueueTicket(ticket *QueueTicket, issue ticketIssue) error {
+	if ticket.WorkflowID != nil {
+		if err := startWorkflow(ticket); err != nil {
+			return err
+		}
+	}
+
 	var service Service
 	if err := database.DB.First(&service, ticket.ServiceID).Error; err != nil {
 		return errors.New("service not found")
@@ -45,7 +128,97 @@ func CreateQueueTicket(ticket *QueueTicket) error {
 		return errors.New("unauthorized: cannot create ticket for this service")
 	}
 
-	return database.DB.Create(ticket).Error
+	if ticket.VenueID == nil {
+		return errors.New("venue_id is required")
+	}
+	if ticket.Priority == "" {
+		ticket.Priority = PriorityRegular
+	}
+	if !IsValidPriority(ticket.Priority) {
+		return errors.New("invalid priority")
+	}
+	if service.VenueID != nil && *service.VenueID != *ticket.VenueID {
+		return errors.New("service does not belong to this venue")
+	}
+
+	venue, err := GetVenueByID(*ticket.VenueID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if issue.booked {
+		closed, err := venue.IsClosedOn(venue.BusinessDate(now))
+		if err != nil {
+			return err
+		}
+		if closed {
+			return ErrVenueClosed
+		}
+	} else {
+		if err := venue.CheckTicketIssuance(now); err != nil {
+			return err
+		}
+		if err := checkCounterSchedules(venue, service.ServiceID, now); err != nil {
+			return err
+		}
+	}
+
+	var displays []QueueDisplay
+	insert := func(tx *gorm.DB) error {
+		if issue.guard != nil {
+			if err := issue.guard(tx); err != nil {
+				return err
+			}
+		}
+
+		number, err := NextQueueSequence(tx, venue.VenueID, service.ServiceID, venue.BusinessDate(now))
+		if err != nil {
+			return err
+		}
+		ticket.QueueNumber = FormatQueueNumber(service.QueuePrefix, service.NumberPadding, number)
+		ticket.Status = TicketStatusWaiting
+		if ticket.QueuedAt == nil {
+			ticket.QueuedAt = &now
+		}
+
+		if err := tx.Create(ticket).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&QueueTicketEvent{TicketID: ticket.TicketID, ToStatus: TicketStatusWaiting}).Error; err != nil {
+			return err
+		}
+
+		displays, err = refreshNextTickets(tx, venue.VenueID, service.ServiceID)
+		if err != nil {
+			return err
+		}
+		if issue.issued != nil {
+			return issue.issued(tx)
+		}
+		return nil
+	}
+
+	// A token collision is astronomically unlikely, but the unique index has the final word
+	for attempt := 1; ; attempt++ {
+		ticket.Token, err = utils.GenerateTicketToken()
+		if err != nil {
+			return errors.New("failed to generate ticket token")
+		}
+
+		err = database.DB.Transaction(insert)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) || attempt == maxTokenAttempts {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	publishTicket(events.TypeTicketCreated, ticket)
+	publishDisplayEvents(displays)
+	return nil
 }
 
 // GetQueueTicketByID retrieves a ticket by ID and user ID
@@ -63,8 +236,13 @@ func GetQueueTicketByID(ticketID uint, userID uint, isAdmin bool) (*QueueTicket,
 	return &ticket, err
 }
 
-// GetQueueTicketByToken retrieves a ticket by its token
+// GetQueueTicketByToken retrieves a ticket by its token.
+// Tokens that fail verification are rejected without querying the database.
 func GetQueueTicketByToken(token string) (*QueueTicket, error) {
+	if !utils.VerifyTicketToken(token) {
+		return nil, errors.New("ticket not found")
+	}
+
 	var ticket QueueTicket
 	err := database.DB.Where("token = ?", token).First(&ticket).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -73,55 +251,386 @@ func GetQueueTicketByToken(token string) (*QueueTicket, error) {
 	return &ticket, err
 }
 
-// UpdateQueueTicket updates a ticket's details
+// maxPostponePlaces caps how far back a customer can move in a single postpone
+const maxPostponePlaces = 10
+
+// ErrPostponeLimitReached is returned when a ticket was already postponed the allowed number of times
+var ErrPostponeLimitReached = errors.New("postpone limit reached for this ticket")
+
+// CancelQueueTicketByToken cancels a ticket on behalf of the customer holding its token
+func CancelQueueTicketByToken(token string, reason string) (*QueueTicket, error) {
+	if reason == "" {
+		reason = "cancelled by customer"
+	}
+
+	var ticket *QueueTicket
+	var displays []QueueDisplay
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ticket, err = lockQueueTicketByToken(tx, token)
+		if err != nil {
+			return err
+		}
+
+		wasWaiting := ticket.Status == TicketStatusWaiting
+		if err := transitionQueueTicket(tx, ticket, TicketStatusCancelled, TicketActor{Reason: reason, ByCustomer: true}); err != nil {
+			return err
+		}
+
+		if wasWaiting && ticket.VenueID != nil && ticket.ServiceID != nil {
+			displays, err = refreshNextTickets(tx, *ticket.VenueID, *ticket.ServiceID)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishTicketEvent(ticket)
+	publishDisplayEvents(displays)
+	return ticket, nil
+}
+
+// PostponeQueueTicketByToken moves a waiting ticket back by the given number of places,
+// or to the end of the queue when fewer tickets are behind it
+func PostponeQueueTicketByToken(token string, places int) (*QueueTicket, error) {
+	if places < 1 || places > maxPostponePlaces {
+		return nil, fmt.Errorf("places must be between 1 and %d", maxPostponePlaces)
+	}
+
+	var ticket *QueueTicket
+	var displays []QueueDisplay
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ticket, err = lockQueueTicketByToken(tx, token)
+		if err != nil {
+			return err
+		}
+		if ticket.Status != TicketStatusWaiting {
+			return errors.New("only waiting tickets can be postponed")
+		}
+		if ticket.VenueID == nil || ticket.ServiceID == nil || ticket.QueuedAt == nil {
+			return errors.New("ticket is not in a service queue")
+		}
+
+		var service Service
+		if err := tx.First(&service, *ticket.ServiceID).Error; err != nil {
+			return errors.New("service not found")
+		}
+		if ticket.PostponeCount >= service.MaxPostpones {
+			return ErrPostponeLimitReached
+		}
+
+		var behind []QueueTicket
+		// Customers only trade places with tickets of their own priority class
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND venue_id = ? AND service_id = ? AND priority = ?", TicketStatusWaiting, *ticket.VenueID, *ticket.ServiceID, ticket.Priority)
+		err = whereBehind(query, ticket).Order(callOrder).Limit(places).Find(&behind).Error
+		if err != nil {
+			return err
+		}
+		if len(behind) == 0 {
+			return errors.New("ticket is already last in the queue")
+		}
+
+		// Rotate the call slots: every ticket passed moves up one slot and this ticket takes the last one
+		slots := []time.Time{*ticket.QueuedAt}
+		for _, other := range behind {
+			slots = append(slots, *other.QueuedAt)
+		}
+		for i, other := range behind {
+			if err := tx.Model(&QueueTicket{}).Where("ticket_id = ?", other.TicketID).Update("queued_at", slots[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		queuedAt := slots[len(behind)]
+		err = tx.Model(&QueueTicket{}).
+			Where("ticket_id = ?", ticket.TicketID).
+			Updates(map[string]interface{}{
+				"queued_at":      queuedAt,
+				"postpone_count": gorm.Expr("postpone_count + 1"),
+			}).Error
+		if err != nil {
+			return err
+		}
+		ticket.QueuedAt = &queuedAt
+		ticket.PostponeCount++
+
+		event := QueueTicketEvent{
+			TicketID:   ticket.TicketID,
+			FromStatus: TicketStatusWaiting,
+			ToStatus:   TicketStatusWaiting,
+			Reason:     fmt.Sprintf("postponed %d places by customer", len(behind)),
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return errors.New("failed to record ticket event: " + err.Error())
+		}
+
+		displays, err = refreshNextTickets(tx, *ticket.VenueID, *ticket.ServiceID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishTicket(events.TypeTicketPostponed, ticket)
+	publishDisplayEvents(displays)
+	return ticket, nil
+}
+
+// UpdateQueueTicket updates a ticket's details. Status changes go through the ticket lifecycle.
 func UpdateQueueTicket(ticket *QueueTicket, userID uint, isAdmin bool) error {
 	if !isAdmin && ticket.UserID != userID {
 		return errors.New("unauthorized: cannot update this ticket")
 	}
 
-	// Only update specific fields without overwriting `created_at`
-	return database.DB.Model(&QueueTicket{}).
-		Where("ticket_id = ? AND user_id = ?", ticket.TicketID, userID).
-		Updates(map[string]interface{}{
-			"status":       ticket.Status,
-			"called_at":    ticket.CalledAt,
-			"completed_at": ticket.CompletedAt,
-		}).Error
+	var updated *QueueTicket
+	var advance *workflowAdvance
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		current, err := lockQueueTicket(tx, ticket.TicketID, userID, isAdmin)
+		if err != nil {
+			return err
+		}
+
+		if ticket.Status == "" || ticket.Status == current.Status {
+			return nil
+		}
+
+		updated = current
+		actor := TicketActor{
+			OperatorID: ticket.OperatorID,
+			CounterID:  ticket.CounterID,
+		}
+		if err := transitionQueueTicket(tx, current, ticket.Status, actor); err != nil {
+			return err
+		}
+		advance, err = advanceWorkflow(tx, current, actor)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if updated != nil {
+		publishTicketCompletion(updated, advance)
+	}
+	return nil
 }
 
-// UpdateQueueTicketStatus updates the status of a ticket and sets the appropriate timestamp
-func UpdateQueueTicketStatus(ticketID uint, status string, operatorID *uint, counterID *uint) error {
-	var updates = map[string]interface{}{
-		"status": status,
+// UpdateQueueTicketStatus moves a ticket to a new status if the lifecycle allows it,
+// setting the matching timestamp and recording the transition
+func UpdateQueueTicketStatus(ticketID uint, status string, operatorID *uint, counterID *uint, reason string) error {
+	var ticket *QueueTicket
+	var advance *workflowAdvance
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ticket, err = lockQueueTicket(tx, ticketID, 0, true)
+		if err != nil {
+			return err
+		}
+
+		actor := TicketActor{
+			OperatorID: operatorID,
+			CounterID:  counterID,
+			Reason:     reason,
+		}
+		if err := transitionQueueTicket(tx, ticket, status, actor); err != nil {
+			return err
+		}
+		advance, err = advanceWorkflow(tx, ticket, actor)
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
-	// Set the appropriate timestamp, operator_id, and counter_id based on the status
-	switch status {
-	case "called":
-		updates["called_at"] = time.Now()
-		updates["operator_id"] = operatorID
-		updates["counter_id"] = counterID
-	case "completed":
-		updates["completed_at"] = time.Now()
-		updates["operator_id"] = operatorID
-		updates["counter_id"] = counterID
-	case "skipped":
-		updates["skipped_at"] = time.Now()
-		updates["operator_id"] = operatorID
-		updates["counter_id"] = counterID
-	case "waiting":
-		// No additional timestamp for waiting
-	default:
-		return errors.New("invalid status")
-	}
-
-	// Update the ticket in the database with operator_id in the WHERE clause for statuses other than "called"
-	query := database.DB.Model(&QueueTicket{}).Where("ticket_id = ?", ticketID)
-	//if status != "called" && operatorID != nil {
-	//	query = query.Where("operator_id = ?", *operatorID)
-	//}
-
-	return query.Updates(updates).Error
+	publishTicketCompletion(ticket, advance)
+	return nil
+}
+
+// CompleteQueueTicket completes a called or serving ticket. A called ticket is moved
+// through serving first so the history still follows the lifecycle.
+// Workflow tickets then join the queue of their next step.
+func CompleteQueueTicket(ticketID uint, operatorID *uint, counterID *uint) (*QueueTicket, error) {
+	var ticket *QueueTicket
+	var advance *workflowAdvance
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ticket, err = lockQueueTicket(tx, ticketID, 0, true)
+		if err != nil {
+			return err
+		}
+
+		actor := TicketActor{OperatorID: operatorID, CounterID: counterID}
+		if ticket.Status == TicketStatusCalled || ticket.Status == TicketStatusRecalled {
+			if err := transitionQueueTicket(tx, ticket, TicketStatusServing, actor); err != nil {
+				return err
+			}
+		}
+
+		if err := transitionQueueTicket(tx, ticket, TicketStatusCompleted, actor); err != nil {
+			return err
+		}
+		advance, err = advanceWorkflow(tx, ticket, actor)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishTicketCompletion(ticket, advance)
+	return ticket, nil
+}
+
+// lockQueueTicketByToken loads a ticket by its token with a row lock for the rest of tx
+func lockQueueTicketByToken(tx *gorm.DB, token string) (*QueueTicket, error) {
+	if !utils.VerifyTicketToken(token) {
+		return nil, errors.New("ticket not found")
+	}
+
+	var ticket QueueTicket
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token = ?", token).First(&ticket).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ticket not found")
+		}
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// lockQueueTicket loads a ticket with a row lock for the rest of tx
+func lockQueueTicket(tx *gorm.DB, ticketID uint, userID uint, isAdmin bool) (*QueueTicket, error) {
+	var ticket QueueTicket
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("ticket_id = ?", ticketID)
+	if !isAdmin {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	if err := query.First(&ticket).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ticket not found")
+		}
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// ErrNoWaitingTickets is returned when there is no ticket left to call
+var ErrNoWaitingTickets = errors.New("no waiting tickets available")
+
+// ErrBatchTooLarge is returned when a batch call asks for more tickets than the service's MaxBatchSize
+var ErrBatchTooLarge = errors.New("batch call exceeds the service's max_batch_size")
+
+// CallNextTicket atomically calls the next waiting ticket across the services the counter is
+// skilled for, see selectNextTicketForCounter. The ticket row is locked so two operators can
+// never call the same ticket, and the counter's QueueDisplay is updated in the same transaction.
+func CallNextTicket(counterID uint, operatorID *uint) (*QueueTicket, *QueueDisplay, error) {
+	tickets, display, err := CallNextTickets(counterID, operatorID, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tickets[0], display, nil
+}
+
+// CallNextTickets atomically calls up to count waiting tickets to the counter at once, e.g. "A-041 to A-045
+// please come to counter 3". The first ticket is chosen like CallNextTicket and the rest follow it in
+// its service's call order; fewer are called when the queue runs out. The tickets share a CallBatchID,
+// are shown together in the display's `CurrentTickets` and are served and completed individually.
+func CallNextTickets(counterID uint, operatorID *uint, count int) ([]QueueTicket, *QueueDisplay, error) {
+	if count < 1 {
+		return nil, nil, errors.New("count must be at least 1")
+	}
+
+	var tickets []QueueTicket
+	var display *QueueDisplay
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var counter Counter
+		if err := tx.First(&counter, counterID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("counter not found")
+			}
+			return err
+		}
+
+		open, err := isCounterOpen(tx, counter.CounterID)
+		if err != nil {
+			return err
+		}
+		if !open {
+			return ErrCounterNotOpen
+		}
+
+		first, err := selectNextTicketForCounter(tx, &counter)
+		if err != nil {
+			return err
+		}
+
+		var service Service
+		if err := tx.First(&service, *first.ServiceID).Error; err != nil {
+			return errors.New("service not found")
+		}
+		if count > 1 && count > service.MaxBatchSize {
+			return fmt.Errorf("%w: at most %d ticket(s) of %s can be called together", ErrBatchTooLarge,
+				service.MaxBatchSize, service.ServiceName)
+		}
+
+		actor := TicketActor{OperatorID: operatorID, CounterID: &counter.CounterID}
+		next := first
+		numbers := make([]string, 0, count)
+		for {
+			if err := transitionQueueTicket(tx, next, TicketStatusCalled, actor); err != nil {
+				return err
+			}
+			tickets = append(tickets, *next)
+			numbers = append(numbers, next.QueueNumber)
+			if len(tickets) == count {
+				break
+			}
+
+			next, err = selectNextTicket(tx, &counter, &service)
+			if errors.Is(err, ErrNoWaitingTickets) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(tickets) > 1 {
+			batchID := tickets[0].TicketID
+			ticketIDs := make([]uint, len(tickets))
+			for i := range tickets {
+				ticketIDs[i] = tickets[i].TicketID
+				tickets[i].CallBatchID = &batchID
+			}
+			err := tx.Model(&QueueTicket{}).
+				Where("ticket_id IN ?", ticketIDs).
+				Update("call_batch_id", batchID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var venueID uint
+		if first.VenueID != nil {
+			venueID = *first.VenueID
+		}
+
+		display, err = syncQueueDisplay(tx, &counter, service.ServiceID, venueID, numbers)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range tickets {
+		publishTicketEvent(&tickets[i])
+	}
+	publishDisplayEvent(display)
+	return tickets, display, nil
 }
 
 // DeleteQueueTicket deletes a ticket by ID
@@ -160,26 +669,16 @@ func GetQueueTicketsByStatus(userID uint, status string, isAdmin bool) ([]QueueT
 	return tickets, err
 }
 
-// GetLastQueueTicketByServiceID retrieves the last ticket for a specific service
-func GetLastQueueTicketByServiceID(serviceID uint) (*QueueTicket, error) {
-	var ticket QueueTicket
-	err := database.DB.Where("service_id = ?", serviceID).
-		Order("queue_number DESC").
-		First(&ticket).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, errors.New("record not found")
-	}
-	return &ticket, err
-}
-
-// IsTokenExists checks if a token already exists in the database
-func IsTokenExists(token string) bool {
+// CountWaitingTickets counts the tickets still waiting for a venue and service
+func CountWaitingTickets(venueID uint, serviceID uint) (int64, error) {
 	var count int64
-	database.DB.Model(&QueueTicket{}).Where("token = ?", token).Count(&count)
-	return count > 0
+	err := database.DB.Model(&QueueTicket{}).
+		Where("status = ? AND venue_id = ? AND service_id = ?", TicketStatusWaiting, venueID, serviceID).
+		Count(&count).Error
+	return count, err
 }
 
-// GetQueueTicketsSorted retrieves queue tickets sorted by queue_number in ascending order
+// GetQueueTicketsSorted retrieves queue tickets in the order they were issued
 func GetQueueTicketsSorted(status string, venueID uint, serviceID uint) ([]QueueTicket, error) {
 	var tickets []QueueTicket
 	query := database.DB.Where("venue_id = ? AND service_id = ?", venueID, serviceID)
@@ -189,7 +688,7 @@ func GetQueueTicketsSorted(status string, venueID uint, serviceID uint) ([]Queue
 		query = query.Where("status = ?", status)
 	}
 
-	err := query.Order("queue_number ASC").Find(&tickets).Error
+	err := query.Order("created_at ASC, ticket_id ASC").Find(&tickets).Error
 	if err != nil {
 		return nil, err
 	}
@@ -199,7 +698,7 @@ func GetQueueTicketsSorted(status string, venueID uint, serviceID uint) ([]Queue
 func GetQueueTicketsByStatusVenueAndService(status string, venueID uint, serviceID uint, lastTopN *int) ([]QueueTicket, error) {
 	var tickets []QueueTicket
 	query := database.DB.Where("status = ? AND venue_id = ? AND service_id = ?", status, venueID, serviceID).
-		Order("queue_number ASC")
+		Order(callOrder)
 
 	if lastTopN != nil && *lastTopN > 0 {
 		query = query.Limit(*lastTopN)
@@ -212,27 +711,15 @@ func GetQueueTicketsByStatusVenueAndService(status string, venueID uint, service
 	return tickets, nil
 }
 
-// CalculateAverageQueuingTime calculates the average queuing time for completed tickets
-func CalculateAverageQueuingTime(venueID uint, serviceID uint) (time.Duration, error) {
-	var tickets []QueueTicket
-	err := database.DB.Where("status = ? AND venue_id = ? AND service_id = ?", "completed", venueID, serviceID).Find(&tickets).Error
-	if err != nil {
+// CalculateAverageQueuingTime returns the average minutes from issue to completion of tickets
+// completed within the recent estimate window
+func CalculateAverageQueuingTime(venueID uint, serviceID uint) (float64, error) {
+	query := database.DB.Model(&QueueTicket{}).
+		Where("status = ? AND venue_id = ? AND service_id = ?", TicketStatusCompleted, venueID, serviceID).
+		Where("completed_at >= ?", time.Now().Add(-estimateWindow))
+	average, err := averageMinutes(query, "created_at", "completed_at")
+	if err != nil || average == nil {
 		return 0, err
 	}
-
-	var totalDuration time.Duration
-	var count int
-	for _, ticket := range tickets {
-		if ticket.CompletedAt != nil {
-			totalDuration += ticket.CompletedAt.Sub(ticket.CreatedAt)
-			count++
-		}
-	}
-
-	if count == 0 {
-		return 0, errors.New("no completed tickets found")
-	}
-
-	averageDuration := totalDuration / time.Duration(count)
-	return time.Duration(averageDuration.Minutes()), nil
+	return *average, nil
 }
@@ -0,0 +1,223 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"queue-system-backend/database"
+	"queue-system-backend/events"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Ticket statuses of the queue ticket lifecycle
+const (
+	TicketStatusWaiting   = "waiting"
+	TicketStatusCalled    = "called"
+	TicketStatusServing   = "serving"
+	TicketStatusCompleted = "completed"
+	TicketStatusSkipped   = "skipped"
+	TicketStatusRecalled  = "recalled"
+	TicketStatusCancelled = "cancelled"
+)
+
+// ticketTransitions lists the statuses each status may move to.
+// Completed and cancelled tickets are final.
+var ticketTransitions = map[string][]string{
+	TicketStatusWaiting:  {TicketStatusCalled, TicketStatusCancelled},
+	TicketStatusCalled:   {TicketStatusServing, TicketStatusSkipped, TicketStatusCancelled},
+	TicketStatusServing:  {TicketStatusCompleted, TicketStatusCancelled},
+	TicketStatusSkipped:  {TicketStatusRecalled, TicketStatusCancelled},
+	TicketStatusRecalled: {TicketStatusServing, TicketStatusSkipped, TicketStatusCancelled},
+}
+
+// ticketStatusTimestamps maps a status to the column stamped when a ticket enters it
+var ticketStatusTimestamps = map[string]string{
+	TicketStatusCalled:    "called_at",
+	TicketStatusServing:   "serving_at",
+	TicketStatusCompleted: "completed_at",
+	TicketStatusSkipped:   "skipped_at",
+	TicketStatusRecalled:  "recalled_at",
+	TicketStatusCancelled: "cancelled_at",
+}
+
+// ErrInvalidTransition is returned when a status change is not allowed by the ticket lifecycle
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// ErrInvalidStatus is returned for statuses that are not part of the ticket lifecycle
+var ErrInvalidStatus = errors.New("invalid status")
+
+// QueueTicketEvent records a single status transition of a ticket
+type QueueTicketEvent struct {
+	EventID    uint      `json:"event_id" gorm:"primaryKey;autoIncrement"`
+	TicketID   uint      `json:"ticket_id" gorm:"not null;index"`   // Foreign key to QueueTickets table
+	FromStatus string    `json:"from_status" gorm:"size:20"`        // Empty for the initial event
+	ToStatus   string    `json:"to_status" gorm:"size:20;not null"` // Status after the transition
+	OperatorID *uint     `json:"operator_id"`                       // User who made the change
+	CounterID  *uint     `json:"counter_id"`                        // Counter the change was made from
+	Reason     string    `json:"reason" gorm:"size:255"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName ensures GORM uses the correct table name
+func (QueueTicketEvent) TableName() string {
+	return "QueueTicketEvents"
+}
+
+// TicketActor describes who performs a ticket transition and why
+type TicketActor struct {
+	OperatorID *uint
+	CounterID  *uint
+	Reason     string
+	ByCustomer bool // Set when the customer acts through their ticket token
+}
+
+// IsValidTicketStatus reports whether status is part of the ticket lifecycle
+func IsValidTicketStatus(status string) bool {
+	_, ok := ticketStatusTimestamps[status]
+	return ok || status == TicketStatusWaiting
+}
+
+// CanTransitionTicket reports whether a ticket may move from one status to another
+func CanTransitionTicket(from, to string) bool {
+	for _, allowed := range ticketTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionQueueTicket moves a locked ticket to a new status and records the event within tx
+func transitionQueueTicket(tx *gorm.DB, ticket *QueueTicket, to string, actor TicketActor) error {
+	if !IsValidTicketStatus(to) {
+		return ErrInvalidStatus
+	}
+	if !CanTransitionTicket(ticket.Status, to) {
+		return fmt.Errorf("%w: cannot move ticket from %s to %s", ErrInvalidTransition, ticket.Status, to)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status": to,
+	}
+	if column, ok := ticketStatusTimestamps[to]; ok {
+		updates[column] = now
+	}
+	// The no-show grace period runs from the latest announcement
+	announced := to == TicketStatusCalled || to == TicketStatusRecalled
+	if announced {
+		updates["announced_at"] = now
+	}
+	if actor.OperatorID != nil {
+		updates["operator_id"] = actor.OperatorID
+	}
+	if actor.CounterID != nil {
+		updates["counter_id"] = actor.CounterID
+	}
+	// Head placement and counter reservations only apply while the ticket waits
+	leftQueue := ticket.Status == TicketStatusWaiting && to != TicketStatusWaiting
+	if leftQueue {
+		updates["at_head"] = false
+		updates["assigned_counter_id"] = nil
+	}
+	cancelledBy := ""
+	if to == TicketStatusCancelled {
+		cancelledBy = "operator"
+		if actor.ByCustomer {
+			cancelledBy = "customer"
+		}
+		updates["cancelled_by"] = cancelledBy
+	}
+
+	result := tx.Model(&QueueTicket{}).
+		Where("ticket_id = ? AND status = ?", ticket.TicketID, ticket.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("ticket status was changed by another request")
+	}
+
+	event := QueueTicketEvent{
+		TicketID:   ticket.TicketID,
+		FromStatus: ticket.Status,
+		ToStatus:   to,
+		OperatorID: actor.OperatorID,
+		CounterID:  actor.CounterID,
+		Reason:     actor.Reason,
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return errors.New("failed to record ticket event: " + err.Error())
+	}
+
+	ticket.Status = to
+	ticket.CancelledBy = cancelledBy
+	ticket.applyStatusTimestamp(to, now)
+	if announced {
+		ticket.AnnouncedAt = &now
+	}
+	if leftQueue {
+		ticket.AtHead = false
+		ticket.AssignedCounterID = nil
+	}
+	if actor.OperatorID != nil {
+		ticket.OperatorID = actor.OperatorID
+	}
+	if actor.CounterID != nil {
+		ticket.CounterID = actor.CounterID
+	}
+	return nil
+}
+
+// GetQueueTicketEvents retrieves the transition history of a ticket in chronological order
+func GetQueueTicketEvents(ticketID uint) ([]QueueTicketEvent, error) {
+	var history []QueueTicketEvent
+	err := database.DB.Where("ticket_id = ?", ticketID).
+		Order("created_at ASC, event_id ASC").
+		Find(&history).Error
+	return history, err
+}
+
+// TicketEventData is the public part of a ticket carried by queue events
+type TicketEventData struct {
+	TicketID    uint   `json:"ticket_id,omitempty"` // Left out of the public display stream
+	QueueNumber string `json:"queue_number"`
+	Status      string `json:"status"`
+	Priority    string `json:"priority"`
+	CounterID   *uint  `json:"counter_id"`
+	RecallCount int    `json:"recall_count"`
+	CallBatchID *uint  `json:"call_batch_id,omitempty"`
+}
+
+// publishTicketEvent notifies displays and consoles that a ticket entered its current status
+func publishTicketEvent(ticket *QueueTicket) {
+	publishTicket(events.TicketEventType(ticket.Status), ticket)
+}
+
+// publishTicket publishes an event of the given type carrying the ticket's public data
+func publishTicket(eventType string, ticket *QueueTicket) {
+	event := events.Event{
+		Type: eventType,
+		Data: TicketEventData{
+			TicketID:    ticket.TicketID,
+			QueueNumber: ticket.QueueNumber,
+			Status:      ticket.Status,
+			Priority:    ticket.Priority,
+			CounterID:   ticket.CounterID,
+			RecallCount: ticket.RecallCount,
+			CallBatchID: ticket.CallBatchID,
+		},
+	}
+	if ticket.VenueID != nil {
+		event.VenueID = *ticket.VenueID
+	}
+	if ticket.ServiceID != nil {
+		event.ServiceID = *ticket.ServiceID
+	}
+	if ticket.CounterID != nil {
+		event.CounterID = *ticket.CounterID
+	}
+	events.Publish(event)
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanTransitionTicket(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{TicketStatusWaiting, TicketStatusCalled, true},
+		{TicketStatusWaiting, TicketStatusCancelled, true},
+		{TicketStatusWaiting, TicketStatusServing, false},
+		{TicketStatusWaiting, TicketStatusCompleted, false},
+		{TicketStatusWaiting, TicketStatusSkipped, false},
+		{TicketStatusCalled, TicketStatusServing, true},
+		{TicketStatusCalled, TicketStatusSkipped, true},
+		{TicketStatusCalled, TicketStatusCancelled, true},
+		{TicketStatusCalled, TicketStatusCompleted, false},
+		{TicketStatusCalled, TicketStatusWaiting, false},
+		{TicketStatusServing, TicketStatusCompleted, true},
+		{TicketStatusServing, TicketStatusCancelled, true},
+		{TicketStatusServing, TicketStatusSkipped, false},
+		{TicketStatusSkipped, TicketStatusRecalled, true},
+		{TicketStatusSkipped, TicketStatusCancelled, true},
+		{TicketStatusSkipped, TicketStatusServing, false},
+		{TicketStatusRecalled, TicketStatusServing, true},
+		{TicketStatusRecalled, TicketStatusSkipped, true},
+		{TicketStatusRecalled, TicketStatusCancelled, true},
+		{TicketStatusRecalled, TicketStatusCompleted, false},
+		{TicketStatusCompleted, TicketStatusWaiting, false},
+		{TicketStatusCompleted, TicketStatusCancelled, false},
+		{TicketStatusCancelled, TicketStatusWaiting, false},
+		{TicketStatusCancelled, TicketStatusCalled, false},
+		{"unknown", TicketStatusCalled, false},
+		{TicketStatusWaiting, "unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := CanTransitionTicket(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransitionTicket(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTicketStatuses(t *testing.T) {
+	for from, targets := range ticketTransitions {
+		if !IsValidTicketStatus(from) {
+			t.Errorf("transition source %q is not a valid status", from)
+		}
+		for _, to := range targets {
+			if _, ok := ticketStatusTimestamps[to]; !ok {
+				t.Errorf("transition target %q has no timestamp column", to)
+			}
+		}
+	}
+
+	tests := []struct {
+		status string
+		valid  bool
+		stamp  func(ticket *QueueTicket) *time.Time
+	}{
+		{TicketStatusWaiting, true, nil},
+		{TicketStatusCalled, true, func(ticket *QueueTicket) *time.Time { return ticket.CalledAt }},
+		{TicketStatusServing, true, func(ticket *QueueTicket) *time.Time { return ticket.ServingAt }},
+		{TicketStatusCompleted, true, func(ticket *QueueTicket) *time.Time { return ticket.CompletedAt }},
+		{TicketStatusSkipped, true, func(ticket *QueueTicket) *time.Time { return ticket.SkippedAt }},
+		{TicketStatusRecalled, true, func(ticket *QueueTicket) *time.Time { return ticket.RecalledAt }},
+		{TicketStatusCancelled, true, func(ticket *QueueTicket) *time.Time { return ticket.CancelledAt }},
+		{"unknown", false, nil},
+		{"", false, nil},
+	}
+
+	at := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := IsValidTicketStatus(tt.status); got != tt.valid {
+				t.Errorf("IsValidTicketStatus(%q) = %v, want %v", tt.status, got, tt.valid)
+			}
+
+			var ticket QueueTicket
+			ticket.applyStatusTimestamp(tt.status, at)
+			if tt.stamp == nil {
+				if ticket != (QueueTicket{}) {
+					t.Errorf("applyStatusTimestamp(%q) set a timestamp", tt.status)
+				}
+				return
+			}
+			if stamped := tt.stamp(&ticket); stamped == nil || !stamped.Equal(at) {
+				t.Errorf("applyStatusTimestamp(%q) stamped %v, want %v", tt.status, stamped, at)
+			}
+		})
+	}
+}
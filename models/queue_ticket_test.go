@@ -0,0 +1,167 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCallNextTickets(t *testing.T) {
+	tests := []struct {
+		name        string
+		waiting     int
+		count       int
+		wantCalled  []string // Queue numbers in call order
+		wantBatched bool
+		wantNext    []string
+		wantErr     error
+	}{
+		{
+			name:        "batch of three",
+			waiting:     5,
+			count:       3,
+			wantCalled:  []string{"A-001", "A-002", "A-003"},
+			wantBatched: true,
+			wantNext:    []string{"A-004", "A-005"},
+		},
+		{
+			name:        "queue runs out",
+			waiting:     2,
+			count:       3,
+			wantCalled:  []string{"A-001", "A-002"},
+			wantBatched: true,
+			wantNext:    []string{},
+		},
+		{
+			name:       "single call has no batch",
+			waiting:    2,
+			count:      1,
+			wantCalled: []string{"A-001"},
+			wantNext:   []string{"A-002"},
+		},
+		{
+			name:    "over the service's max batch size",
+			waiting: 5,
+			count:   4,
+			wantErr: ErrBatchTooLarge,
+		},
+		{
+			name:    "empty queue",
+			count:   2,
+			wantErr: ErrNoWaitingTickets,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			venueID, userID := uint(1), uint(1)
+			service := Service{ServiceID: 1, UserID: &userID, VenueID: &venueID, ServiceName: "Payment", MaxBatchSize: 3}
+			if err := service.validateCallPolicy(); err != nil {
+				t.Fatal(err)
+			}
+			counter := Counter{CounterID: 1, VenueID: &venueID, ServiceID: &service.ServiceID, CounterName: "Counter 1", UserID: userID}
+			session := CounterSession{CounterID: 1, VenueID: &venueID, OperatorID: userID, Status: SessionStatusOpen, OpenedAt: time.Now()}
+			for _, row := range []interface{}{&service, &counter, &session} {
+				if err := db.Create(row).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			start := time.Now().Add(-time.Hour)
+			for i := 0; i < tt.waiting; i++ {
+				queuedAt := start.Add(time.Duration(i) * time.Minute)
+				ticket := QueueTicket{
+					UserID:      userID,
+					ServiceID:   &service.ServiceID,
+					VenueID:     &venueID,
+					QueueNumber: fmt.Sprintf("A-%03d", i+1),
+					Token:       fmt.Sprintf("token-%d", i+1),
+					Status:      TicketStatusWaiting,
+					Priority:    PriorityRegular,
+					QueuedAt:    &queuedAt,
+				}
+				if err := db.Create(&ticket).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			operatorID := userID
+			tickets, display, err := CallNextTickets(counter.CounterID, &operatorID, tt.count)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CallNextTickets() error = %v, want %v", err, tt.wantErr)
+				}
+				var called int64
+				db.Model(&QueueTicket{}).Where("status = ?", TicketStatusCalled).Count(&called)
+				if called != 0 {
+					t.Errorf("%d ticket(s) called after a failed batch call", called)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			called := make([]string, len(tickets))
+			for i, ticket := range tickets {
+				called[i] = ticket.QueueNumber
+				var stored QueueTicket
+				if err := db.First(&stored, ticket.TicketID).Error; err != nil {
+					t.Fatal(err)
+				}
+				if stored.Status != TicketStatusCalled || stored.CounterID == nil || *stored.CounterID != counter.CounterID {
+					t.Errorf("ticket %s is %s at counter %v, want called to counter %d", stored.QueueNumber, stored.Status, stored.CounterID, counter.CounterID)
+				}
+				switch {
+				case tt.wantBatched && (stored.CallBatchID == nil || *stored.CallBatchID != tickets[0].TicketID):
+					t.Errorf("ticket %s has batch %v, want %d", stored.QueueNumber, stored.CallBatchID, tickets[0].TicketID)
+				case !tt.wantBatched && stored.CallBatchID != nil:
+					t.Errorf("ticket %s has batch %d, want none", stored.QueueNumber, *stored.CallBatchID)
+				}
+			}
+			if fmt.Sprint(called) != fmt.Sprint(tt.wantCalled) {
+				t.Errorf("called %v, want %v", called, tt.wantCalled)
+			}
+
+			var current, next []string
+			if err := json.Unmarshal([]byte(display.CurrentTickets), &current); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(display.NextTickets), &next); err != nil {
+				t.Fatal(err)
+			}
+			if display.CurrentTicket != tt.wantCalled[0] || fmt.Sprint(current) != fmt.Sprint(tt.wantCalled) {
+				t.Errorf("display shows %s and %v, want %s and %v", display.CurrentTicket, current, tt.wantCalled[0], tt.wantCalled)
+			}
+			if fmt.Sprint(next) != fmt.Sprint(tt.wantNext) {
+				t.Errorf("display lists %v next, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestQueueDisplayTicketLists(t *testing.T) {
+	db := openTestDB(t)
+	display := QueueDisplay{VenueID: 1, UserID: 1, ServiceID: 1, CounterID: 1, CurrentTicket: "A-001"}
+	if err := CreateQueueDisplay(&display); err != nil {
+		t.Fatal(err)
+	}
+	if display.CurrentTickets != "[]" || display.NextTickets != "[]" {
+		t.Errorf("created display lists %q and %q, want empty JSON arrays", display.CurrentTickets, display.NextTickets)
+	}
+
+	display.CurrentTickets, display.NextTickets = "", `["A-002"]`
+	if err := UpdateQueueDisplay(&display); err != nil {
+		t.Fatal(err)
+	}
+	var stored QueueDisplay
+	if err := db.First(&stored, display.DisplayID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.CurrentTickets != "[]" || stored.NextTickets != `["A-002"]` {
+		t.Errorf("updated display stores %q and %q, want [] and the sent next tickets", stored.CurrentTickets, stored.NextTickets)
+	}
+}
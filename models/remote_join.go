@@ -0,0 +1,244 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"queue-system-backend/database"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// minPhoneDigits is the shortest phone number accepted for remote joining
+const minPhoneDigits = 6
+
+var (
+	// ErrRemoteJoinDisabled is returned when a service doesn't take remote tickets
+	ErrRemoteJoinDisabled = errors.New("remote joining is not available for this service")
+	// ErrQueueFull is returned when a service reached its maximum of waiting tickets for remote joining
+	ErrQueueFull = errors.New("queue is full")
+	// ErrPhoneLimitReached is returned when a phone number took its daily number of remote tickets
+	ErrPhoneLimitReached = errors.New("daily remote ticket limit reached for this phone number")
+	// ErrServeBeforeClose is returned when the waiting queue can't be served before the venue closes
+	ErrServeBeforeClose = errors.New("queue cannot be served before closing time")
+)
+
+// RemoteJoinService is a service customers can join remotely, with its current load
+type RemoteJoinService struct {
+	ServiceID            uint     `json:"service_id"`
+	ServiceName          string   `json:"service_name"`
+	Description          string   `json:"description"`
+	Waiting              int64    `json:"waiting"`
+	EstimatedWaitMinutes *float64 `json:"estimated_wait_minutes"` // Null when there is not enough data
+	Available            bool     `json:"available"`
+	Reason               string   `json:"reason,omitempty"` // Why the service can't be joined right now
+}
+
+// validateRemoteJoin checks the service's remote joining limits
+func (s *Service) validateRemoteJoin() error {
+	if s.RemoteMaxWaiting < 0 || s.RemoteMaxPerPhone < 0 {
+		return errors.New("remote_max_waiting and remote_max_per_phone cannot be negative")
+	}
+	return nil
+}
+
+// normalizePhone strips formatting from a phone number, keeping a leading +
+func normalizePhone(phone string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	var normalized strings.Builder
+	digits := 0
+	for i, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			normalized.WriteRune(r)
+			digits++
+		case r == '+' && i == 0:
+			normalized.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", errors.New("invalid customer_phone")
+		}
+	}
+	if digits < minPhoneDigits || normalized.Len() > 20 {
+		return "", errors.New("invalid customer_phone")
+	}
+	return normalized.String(), nil
+}
+
+// GetRemoteJoinServices lists the venue's services that take remote tickets, with their queue and availability
+func GetRemoteJoinServices(venueID uint) ([]RemoteJoinService, error) {
+	venue, err := GetVenueByID(venueID)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []Service
+	err = database.DB.Where("venue_id = ? AND remote_join_enabled = ?", venueID, true).
+		Order("service_id ASC").
+		Find(&services).Error
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]RemoteJoinService, 0, len(services))
+	for i := range services {
+		service := &services[i]
+		load, err := measureServiceLoad(venue, service.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := RemoteJoinService{
+			ServiceID:   service.ServiceID,
+			ServiceName: service.ServiceName,
+			Description: service.Description,
+			Waiting:     load.waiting,
+			Available:   true,
+		}
+		if load.openCounters > 0 && load.averageMinutes > 0 {
+			wait := float64(load.waiting) * load.averageMinutes / float64(load.openCounters)
+			entry.EstimatedWaitMinutes = &wait
+		}
+		if err := checkRemoteJoin(database.DB, venue, service, load, "", now); err != nil {
+			entry.Available = false
+			entry.Reason = err.Error()
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// JoinQueueRemotely issues a ticket to a customer joining a venue's service queue remotely.
+// The customer's phone number is required so the daily per-phone limit can be enforced.
+func JoinQueueRemotely(venueID uint, ticket *QueueTicket) error {
+	if ticket.ServiceID == nil {
+		return errors.New("service_id is required")
+	}
+
+	service, err := GetServiceByID(*ticket.ServiceID)
+	if err != nil {
+		return err
+	}
+	if service.VenueID == nil || *service.VenueID != venueID || service.UserID == nil || !service.RemoteJoinEnabled {
+		return ErrRemoteJoinDisabled
+	}
+
+	ticket.CustomerPhone, err = normalizePhone(ticket.CustomerPhone)
+	if err != nil {
+		return err
+	}
+
+	venue, err := GetVenueByID(venueID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := venue.CheckTicketIssuance(now); err != nil {
+		return err
+	}
+
+	load, err := measureServiceLoad(venue, service.ServiceID)
+	if err != nil {
+		return err
+	}
+
+	// Remote tickets belong to the service owner and join as regular tickets
+	ticket.UserID = *service.UserID
+	ticket.VenueID = &venue.VenueID
+	ticket.WorkflowID = nil
+	ticket.Priority = PriorityRegular
+	ticket.Remote = true
+
+	// The service row is locked while counting so concurrent joins can't all pass the limits
+	return createQueueTicket(ticket, ticketIssue{guard: func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&Service{}, service.ServiceID).Error; err != nil {
+			return err
+		}
+		load.waiting, err = countWaitingTickets(tx, venue.VenueID, service.ServiceID)
+		if err != nil {
+			return err
+		}
+		return checkRemoteJoin(tx, venue, service, load, ticket.CustomerPhone, now)
+	}})
+}
+
+// serviceLoad is the current queue of a service and the rate it is being served at
+type serviceLoad struct {
+	waiting        int64
+	openCounters   int64
+	averageMinutes float64
+}
+
+// measureServiceLoad counts a service's waiting tickets and open counters and its recent handling time
+func measureServiceLoad(venue *Venue, serviceID uint) (*serviceLoad, error) {
+	load := &serviceLoad{}
+	var err error
+	load.waiting, err = countWaitingTickets(database.DB, venue.VenueID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	load.openCounters, err = CountOpenCounters(venue.VenueID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	load.averageMinutes, err = AverageServiceMinutes(venue.VenueID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	return load, nil
+}
+
+// countWaitingTickets counts the tickets waiting for a service at a venue
+func countWaitingTickets(db *gorm.DB, venueID uint, serviceID uint) (int64, error) {
+	var waiting int64
+	err := db.Model(&QueueTicket{}).
+		Where("status = ? AND venue_id = ? AND service_id = ?", TicketStatusWaiting, venueID, serviceID).
+		Count(&waiting).Error
+	return waiting, err
+}
+
+// checkRemoteJoin applies the service's remote joining guards: the waiting limit, the daily
+// per-phone limit (skipped without a phone) and whether one more ticket can be served before closing.
+// Issued tickets are counted through db, the insert transaction when joining.
+func checkRemoteJoin(db *gorm.DB, venue *Venue, service *Service, load *serviceLoad, phone string, now time.Time) error {
+	if service.RemoteMaxWaiting > 0 && load.waiting >= int64(service.RemoteMaxWaiting) {
+		return ErrQueueFull
+	}
+
+	if phone != "" && service.RemoteMaxPerPhone > 0 {
+		startOfDay, _, err := venue.BusinessDayRange(venue.BusinessDate(now))
+		if err != nil {
+			return err
+		}
+
+		var issued int64
+		err = db.Model(&QueueTicket{}).
+			Where("service_id = ? AND remote = ? AND customer_phone = ? AND created_at >= ?",
+				service.ServiceID, true, phone, startOfDay).
+			Count(&issued).Error
+		if err != nil {
+			return err
+		}
+		if issued >= int64(service.RemoteMaxPerPhone) {
+			return ErrPhoneLimitReached
+		}
+	}
+
+	// Without closing hours, open counters or a measured rate there is nothing to cap against
+	closeAt, open := openingPeriodAt(venue.OpenTime, venue.CloseTime, now.In(venue.Location()))
+	if !open || closeAt == nil || load.openCounters == 0 || load.averageMinutes <= 0 {
+		return nil
+	}
+	remaining := closeAt.Sub(now).Minutes()
+	servable := int64(math.Floor(remaining * float64(load.openCounters) / load.averageMinutes))
+	if load.waiting >= servable {
+		return fmt.Errorf("%w: only about %d ticket(s) can be served before %s", ErrServeBeforeClose,
+			servable, closeAt.Format("15:04"))
+	}
+	return nil
+}
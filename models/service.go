@@ -8,11 +8,61 @@ import (
 )
 
 type Service struct {
-	ServiceID   uint   `json:"service_id" gorm:"primaryKey;autoIncrement"`
-	UserID      *uint  `json:"user_id" gorm:"default:null"`  // Foreign key to Users table
-	VenueID     *uint  `json:"venue_id" gorm:"default:null"` // Foreign key to Venues table
-	ServiceName string `json:"service_name" gorm:"size:255;not null"`
-	Description string `json:"description" gorm:"size:100;default:null"`
+	ServiceID            uint   `json:"service_id" gorm:"primaryKey;autoIncrement"`
+	UserID               *uint  `json:"user_id" gorm:"default:null"`  // Foreign key to Users table
+	VenueID              *uint  `json:"venue_id" gorm:"default:null"` // Foreign key to Venues table
+	ServiceName          string `json:"service_name" gorm:"size:255;not null"`
+	Description          string `json:"description" gorm:"size:100;default:null"`
+	QueuePrefix          string `json:"queue_prefix" gorm:"size:3"`   // Prefix for queue numbers, e.g. "A" for "A-001"
+	NumberPadding        int    `json:"number_padding"`               // Zero-padding width of queue numbers
+	MaxPostpones         int    `json:"max_postpones"`                // Times a customer may postpone their ticket
+	CallPolicy           string `json:"call_policy" gorm:"size:20"`   // CallPolicyStrict or CallPolicyWeighted
+	RegularPerPriority   int    `json:"regular_per_priority"`         // Weighted policy: regular tickets called per priority ticket
+	PriorityAgingMinutes int    `json:"priority_aging_minutes"`       // Waiting longer than this calls a ticket before any class; 0 disables aging
+	RestrictVIPCounters  bool   `json:"restrict_vip_counters"`        // VIP counters only call VIP tickets
+	NoShowGraceSeconds   int    `json:"no_show_grace_seconds"`        // Called tickets not served within this are skipped; 0 disables
+	RejoinPolicy         string `json:"rejoin_policy" gorm:"size:20"` // RejoinNone, RejoinEnd or RejoinOriginal
+	RemoteJoinEnabled    bool   `json:"remote_join_enabled"`          // Customers may take tickets through the public join endpoint
+	RemoteMaxWaiting     int    `json:"remote_max_waiting"`           // Remote joining stops at this many waiting tickets; 0 disables
+	RemoteMaxPerPhone    int    `json:"remote_max_per_phone"`         // Remote tickets per phone number per business day; 0 disables
+	MaxBatchSize         int    `json:"max_batch_size"`               // Most tickets one batch call may call to a counter together
+	SLAWaitMinutes       int    `json:"sla_wait_minutes"`             // SLA: tickets should be called within this wait; 0 disables
+	SLATargetPercent     int    `json:"sla_target_percent"`           // SLA: share of tickets that must meet SLAWaitMinutes
+}
+
+// NewService returns a service with the default numbering, call, rejoin and SLA settings.
+// Defaults live here rather than in the columns so an explicit zero sent on create is kept.
+func NewService() Service {
+	return Service{
+		NumberPadding:      3,
+		MaxPostpones:       2,
+		CallPolicy:         CallPolicyStrict,
+		RegularPerPriority: defaultRegularPerPriority,
+		RejoinPolicy:       RejoinNone,
+		MaxBatchSize:       1,
+		SLATargetPercent:   90,
+	}
+}
+
+// maxQueueNumberLength matches the size of QueueTicket.QueueNumber
+const maxQueueNumberLength = 10
+
+// validateNumbering ensures the prefix and padding produce queue numbers that fit QueueTicket.QueueNumber
+func (s *Service) validateNumbering() error {
+	if s.NumberPadding < 0 {
+		return errors.New("number_padding cannot be negative")
+	}
+	length := s.NumberPadding
+	if s.QueuePrefix != "" {
+		length += len(s.QueuePrefix) + 1
+	}
+	if length > maxQueueNumberLength {
+		return errors.New("queue_prefix and number_padding exceed the queue number length")
+	}
+	if s.MaxPostpones < 0 {
+		return errors.New("max_postpones cannot be negative")
+	}
+	return nil
 }
 
 // TableName ensures GORM uses the correct table name
@@ -43,6 +93,21 @@ func CreateService(service *Service) error {
 	if service.ServiceName == "" {
 		return errors.New("service_name is required")
 	}
+	if err := service.validateNumbering(); err != nil {
+		return err
+	}
+	if err := service.validateCallPolicy(); err != nil {
+		return err
+	}
+	if err := service.validateNoShowPolicy(); err != nil {
+		return err
+	}
+	if err := service.validateRemoteJoin(); err != nil {
+		return err
+	}
+	if err := service.validateSLA(); err != nil {
+		return err
+	}
 	// No validation for description
 	return database.DB.Create(service).Error
 }
@@ -64,6 +129,21 @@ func UpdateService(service *Service) error {
 	if service.ServiceName == "" {
 		return errors.New("service_name is required")
 	}
+	if err := service.validateNumbering(); err != nil {
+		return err
+	}
+	if err := service.validateCallPolicy(); err != nil {
+		return err
+	}
+	if err := service.validateNoShowPolicy(); err != nil {
+		return err
+	}
+	if err := service.validateRemoteJoin(); err != nil {
+		return err
+	}
+	if err := service.validateSLA(); err != nil {
+		return err
+	}
 	// No validation for description
 	return database.DB.Save(service).Error
 }
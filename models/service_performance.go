@@ -0,0 +1,145 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// validateSLA checks the service's SLA target and fills in defaults
+func (s *Service) validateSLA() error {
+	if s.SLAWaitMinutes < 0 {
+		return errors.New("sla_wait_minutes cannot be negative")
+	}
+	if s.SLATargetPercent == 0 {
+		s.SLATargetPercent = 90
+	}
+	if s.SLATargetPercent < 0 || s.SLATargetPercent > 100 {
+		return errors.New("sla_target_percent must be between 1 and 100")
+	}
+	return nil
+}
+
+// Percentiles summarises a distribution of durations in minutes. Values are null without data.
+type Percentiles struct {
+	Count int      `json:"count"`
+	Avg   *float64 `json:"avg"`
+	P50   *float64 `json:"p50"`
+	P90   *float64 `json:"p90"`
+	P95   *float64 `json:"p95"`
+}
+
+// newPercentiles summarises values, sorting them in place
+func newPercentiles(values []float64) Percentiles {
+	return Percentiles{
+		Count: len(values),
+		Avg:   mean(values),
+		P50:   percentile(values, 50),
+		P90:   percentile(values, 90),
+		P95:   percentile(values, 95),
+	}
+}
+
+// SLACompliance reports how many called tickets met the service's wait target
+type SLACompliance struct {
+	WaitMinutes       int      `json:"wait_minutes"`
+	TargetPercent     int      `json:"target_percent"`
+	Called            int      `json:"called"`
+	WithinTarget      int      `json:"within_target"`
+	CompliancePercent *float64 `json:"compliance_percent"` // Null when no ticket was called
+	Met               bool     `json:"met"`
+}
+
+// ServicePerformance holds a service's wait time (joined the service's queue→called) and service time
+// (called→completed) percentiles over a period, with its SLA compliance
+type ServicePerformance struct {
+	ServiceID   uint           `json:"service_id"`
+	ServiceName string         `json:"service_name"`
+	VenueID     uint           `json:"venue_id"`
+	WaitMinutes Percentiles    `json:"wait_minutes"`    // Tickets called in the period
+	ServiceTime Percentiles    `json:"service_minutes"` // Tickets completed in the period
+	SLA         *SLACompliance `json:"sla"`             // Null when the service has no SLA target
+}
+
+// GetServicePerformance computes wait and service time percentiles and SLA compliance per service
+// from the legs called or completed in the period, see loadDurationLegs
+func GetServicePerformance(filter StatisticsFilter, from time.Time, to time.Time) ([]ServicePerformance, error) {
+	tickets, err := loadDurationLegs(filter, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	inPeriod := func(t *time.Time) bool {
+		return t != nil && !t.Before(from) && t.Before(to)
+	}
+
+	waits := map[uint][]float64{}
+	serviceTimes := map[uint][]float64{}
+	for i := range tickets {
+		ticket := &tickets[i]
+		if ticket.ServiceID == nil {
+			continue
+		}
+		serviceID := *ticket.ServiceID
+		if inPeriod(ticket.CalledAt) {
+			waits[serviceID] = append(waits[serviceID], ticket.CalledAt.Sub(ticket.JoinedAt).Minutes())
+		}
+		if inPeriod(ticket.CompletedAt) && ticket.CalledAt != nil {
+			serviceTimes[serviceID] = append(serviceTimes[serviceID], ticket.CompletedAt.Sub(*ticket.CalledAt).Minutes())
+		}
+	}
+
+	serviceIDs := make([]uint, 0, len(waits))
+	for serviceID := range waits {
+		serviceIDs = append(serviceIDs, serviceID)
+	}
+	for serviceID := range serviceTimes {
+		if _, ok := waits[serviceID]; !ok {
+			serviceIDs = append(serviceIDs, serviceID)
+		}
+	}
+	sort.Slice(serviceIDs, func(i, j int) bool { return serviceIDs[i] < serviceIDs[j] })
+
+	results := make([]ServicePerformance, 0, len(serviceIDs))
+	for _, serviceID := range serviceIDs {
+		result := ServicePerformance{ServiceID: serviceID}
+		service, err := GetServiceByID(serviceID)
+		if err != nil && err.Error() != "service not found" {
+			return nil, err
+		}
+		if service != nil {
+			result.ServiceName = service.ServiceName
+			if service.VenueID != nil {
+				result.VenueID = *service.VenueID
+			}
+			if service.SLAWaitMinutes > 0 {
+				result.SLA = measureSLA(service, waits[serviceID])
+			}
+		}
+
+		result.WaitMinutes = newPercentiles(waits[serviceID])
+		result.ServiceTime = newPercentiles(serviceTimes[serviceID])
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// measureSLA counts the waits within the service's SLA target
+func measureSLA(service *Service, waits []float64) *SLACompliance {
+	sla := &SLACompliance{
+		WaitMinutes:   service.SLAWaitMinutes,
+		TargetPercent: service.SLATargetPercent,
+		Called:        len(waits),
+	}
+	for _, wait := range waits {
+		if wait <= float64(service.SLAWaitMinutes) {
+			sla.WithinTarget++
+		}
+	}
+	if sla.Called > 0 {
+		compliance := float64(sla.WithinTarget) / float64(sla.Called) * 100
+		sla.CompliancePercent = &compliance
+		sla.Met = compliance >= float64(service.SLATargetPercent)
+	}
+	return sla
+}
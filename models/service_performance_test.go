@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		p      float64
+		want   *float64
+	}{
+		{"no values", nil, 50, nil},
+		{"single value at p0", []float64{7}, 0, floatPtr(7)},
+		{"single value at p50", []float64{7}, 50, floatPtr(7)},
+		{"single value at p100", []float64{7}, 100, floatPtr(7)},
+		{"p0 is the minimum", []float64{4, 1, 3, 2}, 0, floatPtr(1)},
+		{"p100 is the maximum", []float64{4, 1, 3, 2}, 100, floatPtr(4)},
+		{"median of an even count interpolates", []float64{4, 1, 3, 2}, 50, floatPtr(2.5)},
+		{"median of an odd count is the middle", []float64{5, 1, 3}, 50, floatPtr(3)},
+		{"p25 interpolates between ranks", []float64{4, 1, 3, 2}, 25, floatPtr(1.75)},
+		{"p90 interpolates near the top", []float64{10, 20, 30, 40, 50}, 90, floatPtr(46)},
+		{"equal values", []float64{2, 2, 2}, 90, floatPtr(2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertFloatPtr(t, "percentile", percentile(tt.values, tt.p), tt.want, 1e-9)
+		})
+	}
+}
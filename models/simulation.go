@@ -0,0 +1,641 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"queue-system-backend/database"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSimulatedTickets bounds the tickets of one simulation
+	maxSimulatedTickets = 20000
+	// maxSimulatedCounters bounds the counters of one simulation
+	maxSimulatedCounters = 200
+	// simulationHistoryDays is the history service times are measured over when a ticket has none of its own
+	simulationHistoryDays = 28
+	// waitHistogramMinutes is the width of a wait histogram bucket; the last bucket gathers waits of an hour or more
+	waitHistogramMinutes = 5
+)
+
+// Simulation sources
+const (
+	SimulationSourceHistorical = "historical"
+	SimulationSourceProfile    = "profile"
+)
+
+// ErrInvalidSimulation is returned when a simulation scenario is inconsistent
+var ErrInvalidSimulation = errors.New("invalid simulation scenario")
+
+// SimulationScenario describes a what-if run of a venue's queue for one day. Without a profile the
+// day's real tickets are replayed, keeping their arrival times, priorities and, unless a service
+// overrides it, their measured handling times. Simulated counters are open throughout the day.
+type SimulationScenario struct {
+	Date     string              `json:"date"`     // Business day (YYYY-MM-DD) to replay or lay the profile on; defaults to today
+	Profile  []SimulatedArrivals `json:"profile"`  // Synthetic arrivals replacing the day's tickets
+	Counters []SimulatedCounter  `json:"counters"` // Replace the venue's counters and their skills
+	Services []SimulatedService  `json:"services"` // Override the call policy and service time of services
+	Seed     int64               `json:"seed"`     // Seeds arrival and service times; 0 uses 1 so runs are repeatable
+}
+
+// SimulatedArrivals is a number of tickets issued at random times within one hour
+type SimulatedArrivals struct {
+	ServiceID uint   `json:"service_id"`
+	Hour      int    `json:"hour"` // Hour of the day, 0-23, in the venue's time zone
+	Arrivals  int    `json:"arrivals"`
+	Priority  string `json:"priority"` // Defaults to regular
+}
+
+// SimulatedCounter is one or more identical counters
+type SimulatedCounter struct {
+	Name   string           `json:"name"`
+	Count  int              `json:"count"` // Defaults to 1
+	IsVIP  bool             `json:"is_vip"`
+	Skills []CounterService `json:"skills"` // Services served and their weights; counter_id is ignored
+}
+
+// SimulatedService overrides a service's call policy settings and service time; unset fields keep the service's
+type SimulatedService struct {
+	ServiceID            uint    `json:"service_id"`
+	CallPolicy           string  `json:"call_policy"`
+	RegularPerPriority   int     `json:"regular_per_priority"`
+	PriorityAgingMinutes *int    `json:"priority_aging_minutes"`
+	RestrictVIPCounters  *bool   `json:"restrict_vip_counters"`
+	ServiceMinutes       float64 `json:"service_minutes"` // Average service time of every ticket of the service
+}
+
+// SimulationResult is the outcome of a simulation
+type SimulationResult struct {
+	Date              string                   `json:"date"`
+	Source            string                   `json:"source"` // historical or profile
+	Tickets           int                      `json:"tickets"`
+	Served            int                      `json:"served"`
+	Abandoned         int                      `json:"abandoned"` // Left before being called, as the day's cancelled tickets did
+	Unserved          int                      `json:"unserved"`  // Never called: no counter serves their service
+	MaxQueueLength    int                      `json:"max_queue_length"`
+	WaitMinutes       Percentiles              `json:"wait_minutes"`
+	WaitHistogram     []WaitHistogramBucket    `json:"wait_histogram"`
+	Services          []SimulatedServiceResult `json:"services"`
+	Counters          []SimulatedCounterResult `json:"counters"`
+	ActualWaitMinutes *Percentiles             `json:"actual_wait_minutes"` // The replayed day's real waits, null for a profile
+}
+
+// WaitHistogramBucket counts the served tickets whose wait falls in [FromMinutes, ToMinutes)
+type WaitHistogramBucket struct {
+	FromMinutes int  `json:"from_minutes"`
+	ToMinutes   *int `json:"to_minutes"` // Null for the last, open-ended bucket
+	Tickets     int  `json:"tickets"`
+}
+
+// SimulatedServiceResult is the outcome of a simulation for one service
+type SimulatedServiceResult struct {
+	ServiceID      uint           `json:"service_id"`
+	ServiceName    string         `json:"service_name"`
+	Tickets        int            `json:"tickets"`
+	Served         int            `json:"served"`
+	ServiceMinutes float64        `json:"service_minutes"` // Average service time used for tickets without their own
+	WaitMinutes    Percentiles    `json:"wait_minutes"`
+	SLA            *SLACompliance `json:"sla"` // Null when the service has no SLA target
+}
+
+// SimulatedCounterResult is the outcome of a simulation for one counter
+type SimulatedCounterResult struct {
+	Name        string  `json:"name"`
+	Served      int     `json:"served"`
+	BusyMinutes float64 `json:"busy_minutes"`
+}
+
+// simTicket is a ticket going through the simulation
+type simTicket struct {
+	ticket   QueueTicket // TicketID, ServiceID, Priority and QueuedAt drive the call order
+	leaveAt  *time.Time  // When the customer gives up waiting
+	duration time.Duration
+	waited   *time.Duration
+}
+
+// simCounter is a counter of the simulation
+type simCounter struct {
+	counter Counter
+	skills  []CounterService
+	freeAt  time.Time
+	served  int
+	busy    time.Duration
+}
+
+// simService is a service's effective call policy in the simulation and its recent calls
+type simService struct {
+	service        Service
+	serviceMinutes float64
+	overridden     bool     // ServiceMinutes replaces the tickets' own handling times
+	recent         []string // Priorities of the latest calls, newest last
+}
+
+// simulation is the state of one run
+type simulation struct {
+	tickets  []*simTicket
+	counters []*simCounter
+	services map[uint]*simService
+	rng      *rand.Rand
+}
+
+// SimulateQueue runs a what-if scenario of a venue's day through the call policies and reports the
+// resulting waits. Nothing is written: tickets, counters and services are copied into memory.
+func SimulateQueue(venueID uint, scenario SimulationScenario) (*SimulationResult, error) {
+	venue, err := GetVenueByID(venueID)
+	if err != nil {
+		return nil, err
+	}
+	if scenario.Date == "" {
+		scenario.Date = venue.BusinessDate(time.Now())
+	}
+	dayStart, dayEnd, err := venue.BusinessDayRange(scenario.Date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSimulation, err)
+	}
+	if scenario.Seed == 0 {
+		scenario.Seed = 1
+	}
+
+	sim := &simulation{services: map[uint]*simService{}, rng: rand.New(rand.NewSource(scenario.Seed))}
+	if err := sim.loadServices(venue, scenario.Services, dayEnd); err != nil {
+		return nil, err
+	}
+	if err := sim.loadCounters(venue, scenario.Counters); err != nil {
+		return nil, err
+	}
+
+	result := &SimulationResult{Date: scenario.Date}
+	if len(scenario.Profile) > 0 {
+		result.Source = SimulationSourceProfile
+		err = sim.generateArrivals(venue, scenario.Profile, dayStart)
+	} else {
+		result.Source = SimulationSourceHistorical
+		result.ActualWaitMinutes, err = sim.replayArrivals(venue, dayStart, dayEnd)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sim.run(result)
+	sim.report(result)
+	return result, nil
+}
+
+// loadServices loads the venue's services with the scenario's overrides and their average service times
+func (s *simulation) loadServices(venue *Venue, overrides []SimulatedService, dayEnd time.Time) error {
+	var services []Service
+	if err := database.DB.Where("venue_id = ?", venue.VenueID).Find(&services).Error; err != nil {
+		return err
+	}
+	for _, service := range services {
+		s.services[service.ServiceID] = &simService{service: service}
+	}
+
+	for _, override := range overrides {
+		sim, ok := s.services[override.ServiceID]
+		if !ok {
+			return fmt.Errorf("%w: service %d is not a service of this venue", ErrInvalidSimulation, override.ServiceID)
+		}
+		if override.CallPolicy != "" {
+			sim.service.CallPolicy = override.CallPolicy
+		}
+		if override.RegularPerPriority != 0 {
+			sim.service.RegularPerPriority = override.RegularPerPriority
+		}
+		if override.PriorityAgingMinutes != nil {
+			sim.service.PriorityAgingMinutes = *override.PriorityAgingMinutes
+		}
+		if override.RestrictVIPCounters != nil {
+			sim.service.RestrictVIPCounters = *override.RestrictVIPCounters
+		}
+		if override.ServiceMinutes < 0 {
+			return fmt.Errorf("%w: service_minutes cannot be negative", ErrInvalidSimulation)
+		}
+		if override.ServiceMinutes > 0 {
+			sim.serviceMinutes = override.ServiceMinutes
+			sim.overridden = true
+		}
+	}
+
+	for _, sim := range s.services {
+		if err := sim.service.validateCallPolicy(); err != nil {
+			return fmt.Errorf("%w: service %d: %v", ErrInvalidSimulation, sim.service.ServiceID, err)
+		}
+	}
+
+	// Services without an override use their average handling time over the weeks before the day
+	tickets, err := loadSeriesTickets(StatisticsFilter{VenueID: venue.VenueID}, dayEnd.AddDate(0, 0, -simulationHistoryDays), dayEnd)
+	if err != nil {
+		return err
+	}
+	handling := map[uint][]float64{}
+	for i := range tickets {
+		ticket := &tickets[i]
+		if ticket.ServiceID != nil && ticket.CalledAt != nil && ticket.CompletedAt != nil {
+			handling[*ticket.ServiceID] = append(handling[*ticket.ServiceID], ticket.CompletedAt.Sub(*ticket.CalledAt).Minutes())
+		}
+	}
+	for serviceID, sim := range s.services {
+		if !sim.overridden {
+			if average := mean(handling[serviceID]); average != nil {
+				sim.serviceMinutes = *average
+			}
+		}
+	}
+	return nil
+}
+
+// loadCounters sets up the scenario's counters, or the venue's counters with their skills
+func (s *simulation) loadCounters(venue *Venue, counters []SimulatedCounter) error {
+	if len(counters) == 0 {
+		var venueCounters []Counter
+		if err := database.DB.Where("venue_id = ?", venue.VenueID).Order("counter_id ASC").Find(&venueCounters).Error; err != nil {
+			return err
+		}
+		for _, counter := range venueCounters {
+			skills, err := counterSkills(database.DB, &counter)
+			if err != nil {
+				return err
+			}
+			// Skills for services of other venues can't call any of this venue's tickets
+			venueSkills := []CounterService{}
+			for _, skill := range skills {
+				if _, ok := s.services[skill.ServiceID]; ok {
+					venueSkills = append(venueSkills, skill)
+				}
+			}
+			counters = append(counters, SimulatedCounter{Name: counter.CounterName, IsVIP: counter.IsVIP, Skills: venueSkills})
+		}
+	}
+
+	for _, counter := range counters {
+		if counter.Count == 0 {
+			counter.Count = 1
+		}
+		if counter.Count < 0 || len(s.counters)+counter.Count > maxSimulatedCounters {
+			return fmt.Errorf("%w: between 1 and %d counters can be simulated", ErrInvalidSimulation, maxSimulatedCounters)
+		}
+		for _, skill := range counter.Skills {
+			if _, ok := s.services[skill.ServiceID]; !ok {
+				return fmt.Errorf("%w: service %d is not a service of this venue", ErrInvalidSimulation, skill.ServiceID)
+			}
+			if skill.Weight < 1 {
+				return fmt.Errorf("%w: skill weights must be at least 1", ErrInvalidSimulation)
+			}
+		}
+		for i := 0; i < counter.Count; i++ {
+			name := counter.Name
+			if counter.Count > 1 {
+				name = fmt.Sprintf("%s #%d", counter.Name, i+1)
+			}
+			s.counters = append(s.counters, &simCounter{
+				counter: Counter{CounterName: name, IsVIP: counter.IsVIP},
+				skills:  counter.Skills,
+			})
+		}
+	}
+	if len(s.counters) == 0 {
+		return fmt.Errorf("%w: the venue has no counters to simulate", ErrInvalidSimulation)
+	}
+	return nil
+}
+
+// replayArrivals loads the service legs joined on the day and returns their actual waits. Each leg of a
+// transferred or workflow ticket arrives in its own service's queue.
+func (s *simulation) replayArrivals(venue *Venue, dayStart time.Time, dayEnd time.Time) (*Percentiles, error) {
+	var legs []seriesTicket
+	err := serviceLegs(func(query *gorm.DB) *gorm.DB {
+		return query.Where("venue_id = ? AND joined_at >= ? AND joined_at < ? AND service_id IS NOT NULL", venue.VenueID, dayStart, dayEnd)
+	}).Order("joined_at ASC, ticket_id ASC").Scan(&legs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(legs) > maxSimulatedTickets {
+		return nil, fmt.Errorf("%w: more than %d tickets to simulate", ErrInvalidSimulation, maxSimulatedTickets)
+	}
+
+	var waits []float64
+	for _, leg := range legs {
+		service, ok := s.services[*leg.ServiceID]
+		if !ok {
+			continue
+		}
+		sim := &simTicket{ticket: QueueTicket{
+			TicketID:  leg.TicketID,
+			ServiceID: leg.ServiceID,
+			Priority:  leg.Priority,
+			CreatedAt: leg.JoinedAt,
+		}}
+		sim.ticket.QueuedAt = &sim.ticket.CreatedAt
+		if sim.ticket.Priority == "" {
+			sim.ticket.Priority = PriorityRegular
+		}
+		if leg.CalledAt != nil {
+			waits = append(waits, leg.CalledAt.Sub(leg.JoinedAt).Minutes())
+		} else if leg.CancelledAt != nil {
+			sim.leaveAt = leg.CancelledAt
+		}
+
+		finishedAt := leg.CompletedAt
+		if finishedAt == nil {
+			finishedAt = leg.SkippedAt
+		}
+		if leg.CalledAt != nil && finishedAt != nil && !service.overridden {
+			sim.duration = finishedAt.Sub(*leg.CalledAt)
+		} else {
+			sim.duration = s.sampleDuration(service)
+		}
+		s.tickets = append(s.tickets, sim)
+	}
+
+	actual := newPercentiles(waits)
+	return &actual, s.checkServiceTimes()
+}
+
+// generateArrivals creates the profile's tickets at random times within their hours
+func (s *simulation) generateArrivals(venue *Venue, profile []SimulatedArrivals, dayStart time.Time) error {
+	total := 0
+	for _, entry := range profile {
+		if _, ok := s.services[entry.ServiceID]; !ok {
+			return fmt.Errorf("%w: service %d is not a service of this venue", ErrInvalidSimulation, entry.ServiceID)
+		}
+		if entry.Hour < 0 || entry.Hour > 23 || entry.Arrivals < 0 {
+			return fmt.Errorf("%w: profile hours must be 0-23 with non-negative arrivals", ErrInvalidSimulation)
+		}
+		if entry.Priority == "" {
+			entry.Priority = PriorityRegular
+		}
+		if !IsValidPriority(entry.Priority) {
+			return fmt.Errorf("%w: invalid priority %q", ErrInvalidSimulation, entry.Priority)
+		}
+		if total += entry.Arrivals; total > maxSimulatedTickets {
+			return fmt.Errorf("%w: more than %d tickets to simulate", ErrInvalidSimulation, maxSimulatedTickets)
+		}
+
+		day := time.Date(dayStart.Year(), dayStart.Month(), dayStart.Day(), entry.Hour, 0, 0, 0, venue.Location())
+		serviceID := entry.ServiceID
+		for i := 0; i < entry.Arrivals; i++ {
+			sim := &simTicket{ticket: QueueTicket{
+				ServiceID: &serviceID,
+				Priority:  entry.Priority,
+				CreatedAt: day.Add(time.Duration(s.rng.Int63n(int64(time.Hour)))),
+			}}
+			sim.ticket.QueuedAt = &sim.ticket.CreatedAt
+			sim.duration = s.sampleDuration(s.services[serviceID])
+			s.tickets = append(s.tickets, sim)
+		}
+	}
+
+	// Ticket IDs follow the issue order so ties in the call order resolve as they would live
+	sort.SliceStable(s.tickets, func(i, j int) bool { return s.tickets[i].ticket.CreatedAt.Before(s.tickets[j].ticket.CreatedAt) })
+	for i, sim := range s.tickets {
+		sim.ticket.TicketID = uint(i + 1)
+	}
+	return s.checkServiceTimes()
+}
+
+// checkServiceTimes makes sure every simulated ticket's service has a service time to draw from
+func (s *simulation) checkServiceTimes() error {
+	for _, sim := range s.tickets {
+		if service := s.services[*sim.ticket.ServiceID]; service.serviceMinutes <= 0 && sim.duration == 0 {
+			return fmt.Errorf("%w for service %d, set its service_minutes", ErrNoServiceTimes, service.service.ServiceID)
+		}
+	}
+	return nil
+}
+
+// sampleDuration draws a handling time from an exponential distribution around the service's average
+func (s *simulation) sampleDuration(service *simService) time.Duration {
+	return time.Duration(s.rng.ExpFloat64() * service.serviceMinutes * float64(time.Minute))
+}
+
+// run plays the day: each time a ticket arrives or a counter becomes free, free counters call their next ticket
+func (s *simulation) run(result *SimulationResult) {
+	sort.SliceStable(s.tickets, func(i, j int) bool { return s.tickets[i].ticket.CreatedAt.Before(s.tickets[j].ticket.CreatedAt) })
+	if len(s.tickets) == 0 {
+		return
+	}
+
+	var waiting []*simTicket
+	next := 0
+	now := s.tickets[0].ticket.CreatedAt
+	for {
+		for next < len(s.tickets) && !s.tickets[next].ticket.CreatedAt.After(now) {
+			waiting = append(waiting, s.tickets[next])
+			next++
+		}
+
+		remaining := waiting[:0]
+		for _, sim := range waiting {
+			if sim.leaveAt != nil && !sim.leaveAt.After(now) {
+				result.Abandoned++
+				continue
+			}
+			remaining = append(remaining, sim)
+		}
+		waiting = remaining
+		if len(waiting) > result.MaxQueueLength {
+			result.MaxQueueLength = len(waiting)
+		}
+
+		// Counters finishing instantly may call again at the same time
+		for called := true; called && len(waiting) > 0; {
+			called = false
+			for _, counter := range s.counters {
+				if counter.freeAt.After(now) {
+					continue
+				}
+				index := s.selectNext(counter, waiting, now)
+				if index < 0 {
+					continue
+				}
+				sim := waiting[index]
+				waiting = append(waiting[:index], waiting[index+1:]...)
+
+				waited := now.Sub(sim.ticket.CreatedAt)
+				sim.waited = &waited
+				counter.freeAt = now.Add(sim.duration)
+				counter.busy += sim.duration
+				counter.served++
+				service := s.services[*sim.ticket.ServiceID]
+				service.recent = append(service.recent, sim.ticket.Priority)
+				called = true
+			}
+		}
+
+		var nextAt time.Time
+		if next < len(s.tickets) {
+			nextAt = s.tickets[next].ticket.CreatedAt
+		}
+		for _, counter := range s.counters {
+			if counter.freeAt.After(now) && (nextAt.IsZero() || counter.freeAt.Before(nextAt)) {
+				nextAt = counter.freeAt
+			}
+		}
+		if nextAt.IsZero() {
+			break
+		}
+		now = nextAt
+	}
+
+	// Customers who would still give up leave; the rest are never called
+	for _, sim := range waiting {
+		if sim.leaveAt != nil {
+			result.Abandoned++
+		} else {
+			result.Unserved++
+		}
+	}
+}
+
+// selectNext returns the index of the waiting ticket the counter calls next, or -1. Like
+// selectNextTicketForCounter, each skill proposes a ticket under its service's call policy
+// and the one with the longest weighted waiting time wins.
+func (s *simulation) selectNext(counter *simCounter, waiting []*simTicket, now time.Time) int {
+	best := -1
+	var bestScore float64
+	for _, skill := range counter.skills {
+		service := s.services[skill.ServiceID]
+		candidate := s.selectNextForService(counter, service, waiting, now)
+		if candidate < 0 {
+			continue
+		}
+		score := now.Sub(*waiting[candidate].ticket.QueuedAt).Seconds() * float64(skill.Weight)
+		if best < 0 || score > bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// selectNextForService mirrors selectNextTicket on the tickets in memory
+func (s *simulation) selectNextForService(counter *simCounter, service *simService, waiting []*simTicket, now time.Time) int {
+	eligible := func(ticket *QueueTicket) bool {
+		if *ticket.ServiceID != service.service.ServiceID {
+			return false
+		}
+		return !counter.counter.IsVIP || !service.service.RestrictVIPCounters || ticket.Priority == PriorityVIP
+	}
+
+	var lanes []func(*QueueTicket) bool
+	if service.service.CallPolicy == CallPolicyWeighted {
+		regular := func(ticket *QueueTicket) bool { return ticket.Priority == PriorityRegular }
+		priority := func(ticket *QueueTicket) bool { return ticket.Priority != PriorityRegular }
+		if service.isPriorityTurn() {
+			lanes = append(lanes, priority, regular)
+		} else {
+			lanes = append(lanes, regular, priority)
+		}
+	} else {
+		lanes = append(lanes, func(*QueueTicket) bool { return true })
+	}
+
+	ordering := newCallOrdering(&service.service, now)
+	for _, lane := range lanes {
+		best := -1
+		for i, sim := range waiting {
+			if !eligible(&sim.ticket) || !lane(&sim.ticket) {
+				continue
+			}
+			if best < 0 || ordering.before(&sim.ticket, &waiting[best].ticket) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	return -1
+}
+
+// isPriorityTurn mirrors isPriorityTurn on the simulated calls of the service
+func (s *simService) isPriorityTurn() bool {
+	recent := s.recent
+	if len(recent) > s.service.RegularPerPriority {
+		recent = recent[len(recent)-s.service.RegularPerPriority:]
+	}
+	for _, priority := range recent {
+		if priority != PriorityRegular {
+			return false
+		}
+	}
+	return true
+}
+
+// report summarises the waits of the served tickets overall, per service and per counter
+func (s *simulation) report(result *SimulationResult) {
+	result.Tickets = len(s.tickets)
+
+	var waits []float64
+	byService := map[uint][]float64{}
+	ticketsByService := map[uint]int{}
+	for _, sim := range s.tickets {
+		serviceID := *sim.ticket.ServiceID
+		ticketsByService[serviceID]++
+		if sim.waited == nil {
+			continue
+		}
+		wait := sim.waited.Minutes()
+		waits = append(waits, wait)
+		byService[serviceID] = append(byService[serviceID], wait)
+	}
+	result.Served = len(waits)
+	result.WaitHistogram = waitHistogram(waits)
+	result.WaitMinutes = newPercentiles(waits)
+
+	serviceIDs := make([]uint, 0, len(ticketsByService))
+	for serviceID := range ticketsByService {
+		serviceIDs = append(serviceIDs, serviceID)
+	}
+	sort.Slice(serviceIDs, func(i, j int) bool { return serviceIDs[i] < serviceIDs[j] })
+
+	result.Services = make([]SimulatedServiceResult, 0, len(serviceIDs))
+	for _, serviceID := range serviceIDs {
+		service := s.services[serviceID]
+		entry := SimulatedServiceResult{
+			ServiceID:      serviceID,
+			ServiceName:    service.service.ServiceName,
+			Tickets:        ticketsByService[serviceID],
+			Served:         len(byService[serviceID]),
+			ServiceMinutes: service.serviceMinutes,
+		}
+		if service.service.SLAWaitMinutes > 0 {
+			entry.SLA = measureSLA(&service.service, byService[serviceID])
+		}
+		entry.WaitMinutes = newPercentiles(byService[serviceID])
+		result.Services = append(result.Services, entry)
+	}
+
+	result.Counters = make([]SimulatedCounterResult, len(s.counters))
+	for i, counter := range s.counters {
+		result.Counters[i] = SimulatedCounterResult{
+			Name:        counter.counter.CounterName,
+			Served:      counter.served,
+			BusyMinutes: counter.busy.Minutes(),
+		}
+	}
+}
+
+// waitHistogram counts waits per waitHistogramMinutes bucket up to an hour, then in one open bucket
+func waitHistogram(waits []float64) []WaitHistogramBucket {
+	buckets := make([]WaitHistogramBucket, 0, 60/waitHistogramMinutes+1)
+	for from := 0; from < 60; from += waitHistogramMinutes {
+		to := from + waitHistogramMinutes
+		buckets = append(buckets, WaitHistogramBucket{FromMinutes: from, ToMinutes: &to})
+	}
+	buckets = append(buckets, WaitHistogramBucket{FromMinutes: 60})
+
+	for _, wait := range waits {
+		index := int(wait) / waitHistogramMinutes
+		if index >= len(buckets) {
+			index = len(buckets) - 1
+		}
+		buckets[index].Tickets++
+	}
+	return buckets
+}
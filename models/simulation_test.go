@@ -0,0 +1,187 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitHistogram(t *testing.T) {
+	tests := []struct {
+		name  string
+		waits []float64
+		want  map[int]int // Tickets per bucket start; other buckets are empty
+	}{
+		{"no waits", nil, map[int]int{}},
+		{"bucket edges", []float64{0, 4.99, 5, 9.5, 10}, map[int]int{0: 2, 5: 2, 10: 1}},
+		{"last closed bucket", []float64{55, 59.99}, map[int]int{55: 2}},
+		{"an hour or more is open-ended", []float64{60, 64, 65, 240}, map[int]int{60: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buckets := waitHistogram(tt.waits)
+			if len(buckets) != 13 {
+				t.Fatalf("len(buckets) = %d, want 13", len(buckets))
+			}
+			for i, bucket := range buckets {
+				if bucket.FromMinutes != i*waitHistogramMinutes {
+					t.Errorf("bucket %d starts at %d, want %d", i, bucket.FromMinutes, i*waitHistogramMinutes)
+				}
+				last := i == len(buckets)-1
+				if last && bucket.ToMinutes != nil {
+					t.Errorf("last bucket ends at %d, want open-ended", *bucket.ToMinutes)
+				}
+				if !last && (bucket.ToMinutes == nil || *bucket.ToMinutes != bucket.FromMinutes+waitHistogramMinutes) {
+					t.Errorf("bucket %d ends at %v, want %d", i, bucket.ToMinutes, bucket.FromMinutes+waitHistogramMinutes)
+				}
+				if bucket.Tickets != tt.want[bucket.FromMinutes] {
+					t.Errorf("bucket from %d has %d tickets, want %d", bucket.FromMinutes, bucket.Tickets, tt.want[bucket.FromMinutes])
+				}
+			}
+		})
+	}
+}
+
+// testTicket is a simulated ticket for TestSimulationRun, in minutes from the start of the run
+type testTicket struct {
+	service  uint
+	priority string
+	arrive   int
+	duration int
+	leave    int // Gives up waiting at this minute when set
+}
+
+func TestSimulationRun(t *testing.T) {
+	serving := func(serviceIDs ...uint) *simCounter {
+		counter := &simCounter{}
+		for _, serviceID := range serviceIDs {
+			counter.skills = append(counter.skills, CounterService{ServiceID: serviceID, Weight: 1})
+		}
+		return counter
+	}
+	everyFourMinutes := make([]testTicket, 10)
+	for i := range everyFourMinutes {
+		everyFourMinutes[i] = testTicket{service: 1, arrive: 4 * i, duration: 6}
+	}
+
+	tests := []struct {
+		name          string
+		counters      []*simCounter
+		tickets       []testTicket
+		wantWaits     []int // Minutes per ticket, -1 when not served
+		wantAbandoned int
+		wantUnserved  int
+		wantMaxQueue  int
+	}{
+		{
+			name:         "one counter falls behind arrivals faster than its service",
+			counters:     []*simCounter{serving(1)},
+			tickets:      everyFourMinutes,
+			wantWaits:    []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18},
+			wantMaxQueue: 4,
+		},
+		{
+			name:         "two counters keep up",
+			counters:     []*simCounter{serving(1), serving(1)},
+			tickets:      everyFourMinutes,
+			wantWaits:    []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			wantMaxQueue: 1,
+		},
+		{
+			name:     "priority ticket is called before earlier regular ones",
+			counters: []*simCounter{serving(1)},
+			tickets: []testTicket{
+				{service: 1, priority: PriorityRegular, arrive: 0, duration: 5},
+				{service: 1, priority: PriorityRegular, arrive: 1, duration: 5},
+				{service: 1, priority: PriorityVIP, arrive: 2, duration: 5},
+			},
+			wantWaits:    []int{0, 9, 3},
+			wantMaxQueue: 2,
+		},
+		{
+			name:     "customer leaves before being called",
+			counters: []*simCounter{serving(1)},
+			tickets: []testTicket{
+				{service: 1, arrive: 0, duration: 10},
+				{service: 1, arrive: 1, duration: 5, leave: 5},
+				{service: 1, arrive: 2, duration: 5},
+			},
+			wantWaits:     []int{0, -1, 8},
+			wantAbandoned: 1,
+			wantMaxQueue:  2,
+		},
+		{
+			name:     "service without a counter is never called",
+			counters: []*simCounter{serving(1)},
+			tickets: []testTicket{
+				{service: 2, arrive: 0, duration: 5},
+				{service: 1, arrive: 1, duration: 5},
+			},
+			wantWaits:    []int{-1, 0},
+			wantUnserved: 1,
+			wantMaxQueue: 2,
+		},
+	}
+
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := &simulation{services: map[uint]*simService{}}
+			for _, serviceID := range []uint{1, 2} {
+				service := Service{ServiceID: serviceID}
+				if err := service.validateCallPolicy(); err != nil {
+					t.Fatal(err)
+				}
+				sim.services[serviceID] = &simService{service: service}
+			}
+			sim.counters = tt.counters
+
+			for i, entry := range tt.tickets {
+				serviceID := entry.service
+				ticket := &simTicket{
+					ticket: QueueTicket{
+						TicketID:  uint(i + 1),
+						ServiceID: &serviceID,
+						Priority:  entry.priority,
+						CreatedAt: at(entry.arrive),
+					},
+					duration: time.Duration(entry.duration) * time.Minute,
+				}
+				if ticket.ticket.Priority == "" {
+					ticket.ticket.Priority = PriorityRegular
+				}
+				ticket.ticket.QueuedAt = &ticket.ticket.CreatedAt
+				if entry.leave > 0 {
+					leaveAt := at(entry.leave)
+					ticket.leaveAt = &leaveAt
+				}
+				sim.tickets = append(sim.tickets, ticket)
+			}
+			tickets := append([]*simTicket(nil), sim.tickets...)
+
+			var result SimulationResult
+			sim.run(&result)
+
+			for i, ticket := range tickets {
+				got := -1
+				if ticket.waited != nil {
+					got = int(ticket.waited.Minutes())
+				}
+				if got != tt.wantWaits[i] {
+					t.Errorf("ticket %d waited %d minutes, want %d", i+1, got, tt.wantWaits[i])
+				}
+			}
+			if result.Abandoned != tt.wantAbandoned {
+				t.Errorf("Abandoned = %d, want %d", result.Abandoned, tt.wantAbandoned)
+			}
+			if result.Unserved != tt.wantUnserved {
+				t.Errorf("Unserved = %d, want %d", result.Unserved, tt.wantUnserved)
+			}
+			if result.MaxQueueLength != tt.wantMaxQueue {
+				t.Errorf("MaxQueueLength = %d, want %d", result.MaxQueueLength, tt.wantMaxQueue)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// maxHeatmapDays bounds the history an arrivals heatmap is built from
+const maxHeatmapDays = 366
+
+// maxRecommendedCounters bounds the staffing search; loads needing more are reported as unmet
+const maxRecommendedCounters = 200
+
+// ErrNoServiceTimes is returned when a staffing recommendation has no completed tickets to measure service times from
+var ErrNoServiceTimes = errors.New("no completed tickets to measure the service time from")
+
+// ArrivalHeatmap counts ticket arrivals per hour of the week over a period
+type ArrivalHeatmap struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Timezone string        `json:"timezone"`
+	Cells    []HeatmapCell `json:"cells"` // 168 cells, Monday 00:00 first
+}
+
+// HeatmapCell is one hour of the week
+type HeatmapCell struct {
+	Weekday     int     `json:"weekday"` // 0 is Monday
+	Hour        int     `json:"hour"`
+	Arrivals    int     `json:"arrivals"`     // Tickets issued in this hour over the whole period
+	Occurrences int     `json:"occurrences"`  // Times this hour of the week occurs in the period
+	AvgArrivals float64 `json:"avg_arrivals"` // Arrivals per occurrence, i.e. the hourly arrival rate
+}
+
+// StaffingQuery selects the history and the wait time target of a staffing recommendation
+type StaffingQuery struct {
+	ServiceID     uint
+	From          time.Time
+	To            time.Time
+	Location      *time.Location
+	WaitMinutes   int // Overrides the service's SLA wait when set
+	TargetPercent int // Overrides the service's SLA target when set
+}
+
+// StaffingRecommendation is the number of counters needed in each hour of the week to meet a wait time target
+type StaffingRecommendation struct {
+	ServiceID         uint           `json:"service_id"`
+	WaitMinutes       int            `json:"wait_minutes"`
+	TargetPercent     int            `json:"target_percent"`
+	AvgServiceMinutes float64        `json:"avg_service_minutes"` // Measured over the completed tickets of the period
+	ServiceSamples    int            `json:"service_samples"`
+	Hours             []StaffingHour `json:"hours"` // 168 hours, Monday 00:00 first
+}
+
+// StaffingHour is the Erlang C recommendation for one hour of the week
+type StaffingHour struct {
+	Weekday           int      `json:"weekday"` // 0 is Monday
+	Hour              int      `json:"hour"`
+	ArrivalsPerHour   float64  `json:"arrivals_per_hour"`
+	OfferedLoad       float64  `json:"offered_load"` // Erlangs: arrivals per hour times service hours
+	Counters          int      `json:"counters"`
+	ServiceLevel      *float64 `json:"service_level"`      // Predicted percent called within the wait target, null without arrivals
+	PredictedWaitAvg  *float64 `json:"predicted_wait_avg"` // Predicted average wait in minutes, null without arrivals
+	TargetUnreachable bool     `json:"target_unreachable"` // Even the maximum number of counters misses the target
+}
+
+// GetArrivalHeatmap counts the tickets issued in each hour of the week, in the given time zone.
+// Filtered on a service, it counts the tickets joining the service's queue, transfers included.
+func GetArrivalHeatmap(filter StatisticsFilter, from time.Time, to time.Time, location *time.Location) (*ArrivalHeatmap, error) {
+	if location == nil {
+		location = time.Local
+	}
+	if to.Sub(from) > maxHeatmapDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: the period spans more than %d days", ErrInvalidSeriesQuery, maxHeatmapDays)
+	}
+
+	tickets, err := loadSeriesTickets(filter, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return buildArrivalHeatmap(tickets, filter.ServiceID != 0, from, to, location), nil
+}
+
+// buildArrivalHeatmap counts the arrivals of tickets per hour of the week and how often each hour occurs.
+// Arrivals are the tickets issued, or with joins every leg joining a queue.
+func buildArrivalHeatmap(tickets []seriesTicket, joins bool, from time.Time, to time.Time, location *time.Location) *ArrivalHeatmap {
+	heatmap := &ArrivalHeatmap{From: from, To: to, Timezone: location.String(), Cells: make([]HeatmapCell, 7*24)}
+	for i := range heatmap.Cells {
+		heatmap.Cells[i].Weekday = i / 24
+		heatmap.Cells[i].Hour = i % 24
+	}
+
+	// Only whole hours within the period count as occurrences
+	for start := bucketStart(from, BucketHour, location); start.Before(to); start = start.Add(time.Hour) {
+		if !start.Before(from) && !start.Add(time.Hour).After(to) {
+			heatmap.Cells[hourOfWeek(start, location)].Occurrences++
+		}
+	}
+
+	for i := range tickets {
+		arrived := tickets[i].IssuedAt
+		if joins {
+			arrived = &tickets[i].JoinedAt
+		}
+		if arrived != nil && !arrived.Before(from) && arrived.Before(to) {
+			heatmap.Cells[hourOfWeek(*arrived, location)].Arrivals++
+		}
+	}
+
+	for i := range heatmap.Cells {
+		cell := &heatmap.Cells[i]
+		if cell.Occurrences > 0 {
+			cell.AvgArrivals = float64(cell.Arrivals) / float64(cell.Occurrences)
+		}
+	}
+	return heatmap
+}
+
+// hourOfWeek returns the index of t's hour in the week, Monday 00:00 being 0
+func hourOfWeek(t time.Time, location *time.Location) int {
+	t = t.In(location)
+	return ((int(t.Weekday())+6)%7)*24 + t.Hour()
+}
+
+// GetStaffingRecommendation recommends the number of counters needed in each hour of the week for a
+// service to call its target share of tickets within its wait time. Arrival rates come from the
+// period's heatmap and the service time from its completed tickets, under an Erlang C model.
+func GetStaffingRecommendation(query StaffingQuery) (*StaffingRecommendation, error) {
+	if query.Location == nil {
+		query.Location = time.Local
+	}
+	if query.To.Sub(query.From) > maxHeatmapDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: the period spans more than %d days", ErrInvalidSeriesQuery, maxHeatmapDays)
+	}
+
+	service, err := GetServiceByID(query.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	recommendation := &StaffingRecommendation{
+		ServiceID:     service.ServiceID,
+		WaitMinutes:   service.SLAWaitMinutes,
+		TargetPercent: service.SLATargetPercent,
+	}
+	if query.WaitMinutes > 0 {
+		recommendation.WaitMinutes = query.WaitMinutes
+	}
+	if query.TargetPercent > 0 {
+		recommendation.TargetPercent = query.TargetPercent
+	}
+	if recommendation.WaitMinutes <= 0 {
+		return nil, fmt.Errorf("%w: the service has no SLA wait target, wait_minutes is required", ErrInvalidSeriesQuery)
+	}
+	if recommendation.TargetPercent <= 0 || recommendation.TargetPercent > 100 {
+		return nil, fmt.Errorf("%w: target_percent must be between 1 and 100", ErrInvalidSeriesQuery)
+	}
+
+	tickets, err := loadSeriesTickets(StatisticsFilter{ServiceID: service.ServiceID}, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	var serviceTimes []float64
+	for i := range tickets {
+		ticket := &tickets[i]
+		if ticket.CalledAt != nil && ticket.CompletedAt != nil &&
+			!ticket.CompletedAt.Before(query.From) && ticket.CompletedAt.Before(query.To) {
+			serviceTimes = append(serviceTimes, ticket.CompletedAt.Sub(*ticket.CalledAt).Minutes())
+		}
+	}
+	average := mean(serviceTimes)
+	if average == nil || *average <= 0 {
+		return nil, ErrNoServiceTimes
+	}
+	recommendation.AvgServiceMinutes = *average
+	recommendation.ServiceSamples = len(serviceTimes)
+
+	heatmap := buildArrivalHeatmap(tickets, true, query.From, query.To, query.Location)
+	recommendation.Hours = make([]StaffingHour, len(heatmap.Cells))
+	for i, cell := range heatmap.Cells {
+		recommendation.Hours[i] = recommendStaffing(cell, recommendation.AvgServiceMinutes,
+			float64(recommendation.WaitMinutes), float64(recommendation.TargetPercent))
+	}
+	return recommendation, nil
+}
+
+// recommendStaffing finds the fewest counters whose predicted service level meets the target for one hour
+func recommendStaffing(cell HeatmapCell, serviceMinutes float64, waitMinutes float64, targetPercent float64) StaffingHour {
+	hour := StaffingHour{
+		Weekday:         cell.Weekday,
+		Hour:            cell.Hour,
+		ArrivalsPerHour: cell.AvgArrivals,
+		OfferedLoad:     cell.AvgArrivals * serviceMinutes / 60,
+	}
+	if hour.OfferedLoad == 0 {
+		return hour
+	}
+
+	// The queue is only stable with more counters than the offered load
+	for counters := int(math.Floor(hour.OfferedLoad)) + 1; counters <= maxRecommendedCounters; counters++ {
+		waitProbability := erlangC(counters, hour.OfferedLoad)
+		level := (1 - waitProbability*math.Exp(-(float64(counters)-hour.OfferedLoad)*waitMinutes/serviceMinutes)) * 100
+		averageWait := waitProbability * serviceMinutes / (float64(counters) - hour.OfferedLoad)
+		hour.Counters, hour.ServiceLevel, hour.PredictedWaitAvg = counters, &level, &averageWait
+		if level >= targetPercent {
+			return hour
+		}
+	}
+	hour.TargetUnreachable = true
+	return hour
+}
+
+// erlangC returns the probability that an arrival has to wait with the given counters and offered
+// load in Erlangs, computed from the Erlang B recursion to stay stable for large counter numbers
+func erlangC(counters int, load float64) float64 {
+	blocking := 1.0
+	for n := 1; n <= counters; n++ {
+		blocking = load * blocking / (float64(n) + load*blocking)
+	}
+	return float64(counters) * blocking / (float64(counters) - load*(1-blocking))
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestErlangC(t *testing.T) {
+	tests := []struct {
+		name     string
+		counters int
+		load     float64
+		want     float64
+	}{
+		{"single counter is the utilisation", 1, 0.5, 0.5},
+		{"two counters one erlang", 2, 1, 1.0 / 3},
+		{"three counters two erlangs", 3, 2, 4.0 / 9},
+		{"four counters two erlangs", 4, 2, 4.0 / 23},
+		{"eleven counters ten erlangs", 11, 10, 0.682118},
+		{"twenty counters fifteen erlangs", 20, 15, 0.160429},
+		{"no load never waits", 3, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := erlangC(tt.counters, tt.load); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("erlangC(%d, %v) = %v, want %v", tt.counters, tt.load, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendStaffing(t *testing.T) {
+	tests := []struct {
+		name           string
+		arrivals       float64 // Per hour
+		serviceMinutes float64
+		waitMinutes    float64
+		targetPercent  float64
+		wantCounters   int
+		wantLevel      *float64
+		wantWait       *float64
+		wantUnreached  bool
+	}{
+		{
+			name:     "no arrivals needs no counters",
+			arrivals: 0, serviceMinutes: 6, waitMinutes: 5, targetPercent: 80,
+		},
+		{
+			name:     "two erlangs at 80 percent within 5 minutes",
+			arrivals: 20, serviceMinutes: 6, waitMinutes: 5, targetPercent: 80,
+			wantCounters: 3, wantLevel: floatPtr(80.6845), wantWait: floatPtr(8.0 / 3),
+		},
+		{
+			name:     "two erlangs at 90 percent within 5 minutes",
+			arrivals: 20, serviceMinutes: 6, waitMinutes: 5, targetPercent: 90,
+			wantCounters: 4, wantLevel: floatPtr(96.7152), wantWait: floatPtr(12.0 / 23),
+		},
+		{
+			name:     "load beyond the counter bound is unreachable",
+			arrivals: 2500, serviceMinutes: 6, waitMinutes: 5, targetPercent: 80,
+			wantUnreached: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cell := HeatmapCell{Weekday: 2, Hour: 10, AvgArrivals: tt.arrivals}
+			got := recommendStaffing(cell, tt.serviceMinutes, tt.waitMinutes, tt.targetPercent)
+
+			if got.Weekday != 2 || got.Hour != 10 {
+				t.Errorf("hour = %d/%d, want 2/10", got.Weekday, got.Hour)
+			}
+			if want := tt.arrivals * tt.serviceMinutes / 60; math.Abs(got.OfferedLoad-want) > 1e-9 {
+				t.Errorf("OfferedLoad = %v, want %v", got.OfferedLoad, want)
+			}
+			if got.Counters != tt.wantCounters {
+				t.Errorf("Counters = %d, want %d", got.Counters, tt.wantCounters)
+			}
+			if got.TargetUnreachable != tt.wantUnreached {
+				t.Errorf("TargetUnreachable = %v, want %v", got.TargetUnreachable, tt.wantUnreached)
+			}
+			assertFloatPtr(t, "ServiceLevel", got.ServiceLevel, tt.wantLevel, 1e-4)
+			assertFloatPtr(t, "PredictedWaitAvg", got.PredictedWaitAvg, tt.wantWait, 1e-4)
+		})
+	}
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+// assertFloatPtr compares two optional values within tolerance
+func assertFloatPtr(t *testing.T, name string, got *float64, want *float64, tolerance float64) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("%s = %v, want %v", name, got, want)
+	case math.Abs(*got-*want) > tolerance:
+		t.Errorf("%s = %v, want %v", name, *got, *want)
+	}
+}
@@ -3,28 +3,65 @@ package models
 import (
 	"queue-system-backend/database"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type QueueStatistics struct {
-	CounterID    uint      `json:"counter_id" gorm:"column:counter_id"`
-	ServiceID    uint      `json:"service_id" gorm:"column:service_id"`
-	VenueID      uint      `json:"venue_id" gorm:"column:venue_id"`
-	ActiveQueues int       `json:"active_queues"`
-	WaitTime     float64   `json:"wait_time"`
-	TotalServed  int       `json:"total_served"`
-	CreatedAt    time.Time `json:"created_at"`
+	CounterID         uint      `json:"counter_id" gorm:"column:counter_id"`
+	ServiceID         uint      `json:"service_id" gorm:"column:service_id"`
+	VenueID           uint      `json:"venue_id" gorm:"column:venue_id"`
+	ActiveQueues      int       `json:"active_queues"`
+	WaitTime          float64   `json:"wait_time"`
+	TotalServed       int       `json:"total_served"`
+	CustomerCancelled int       `json:"customer_cancelled"` // Cancelled by the customer, not skipped
+	TransferredIn     int       `json:"transferred_in"`     // Tickets transferred into the service
+	TransferredOut    int       `json:"transferred_out"`    // Tickets transferred out of the service
+	CreatedAt         time.Time `json:"created_at"`
 }
 
 type StatisticsFilter struct {
-	CounterID uint
-	ServiceID uint
-	VenueID   uint
+	CounterID  uint
+	ServiceID  uint
+	VenueID    uint
+	OperatorID uint
+	// Restrict the tickets to these venues and operators when not nil, e.g. the ones a user can see
+	VenueIDs    []uint
+	OperatorIDs []uint
+	// With OperatorIDs, also keeps the tickets called without an operator at these counters
+	OperatorCounterIDs []uint
 }
 
 func (QueueStatistics) TableName() string {
 	return "QueueTickets"
 }
 
+// apply restricts a query on tickets or service legs to the filter
+func (f StatisticsFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.VenueID != 0 {
+		query = query.Where("venue_id = ?", f.VenueID)
+	}
+	if f.ServiceID != 0 {
+		query = query.Where("service_id = ?", f.ServiceID)
+	}
+	if f.CounterID != 0 {
+		query = query.Where("counter_id = ?", f.CounterID)
+	}
+	if f.OperatorID != 0 {
+		query = query.Where("operator_id = ?", f.OperatorID)
+	}
+	if f.VenueIDs != nil {
+		query = query.Where("venue_id IN ?", f.VenueIDs)
+	}
+	switch {
+	case f.OperatorIDs != nil && f.OperatorCounterIDs != nil:
+		query = query.Where("operator_id IN ? OR (operator_id IS NULL AND counter_id IN ?)", f.OperatorIDs, f.OperatorCounterIDs)
+	case f.OperatorIDs != nil:
+		query = query.Where("operator_id IN ?", f.OperatorIDs)
+	}
+	return query
+}
+
 func (s *QueueStatistics) GetActiveQueues(filter StatisticsFilter) ([]QueueStatistics, error) {
 	var stats []QueueStatistics
 	query := database.DB.Table("QueueTickets").
@@ -67,11 +104,23 @@ func (s *QueueStatistics) GetAverageWaitTime(filter StatisticsFilter) ([]QueueSt
 	return stats, err
 }
 
+// GetTotalServed counts the completed tickets per service leg, so workflow steps count in each step's service
 func (s *QueueStatistics) GetTotalServed(filter StatisticsFilter) ([]QueueStatistics, error) {
+	var stats []QueueStatistics
+	query := serviceLegs(func(query *gorm.DB) *gorm.DB {
+		return filter.apply(query.Where("completed_at IS NOT NULL"))
+	})
+	err := query.Select("counter_id, service_id, venue_id, COUNT(*) as total_served").
+		Group("venue_id, service_id, counter_id").
+		Find(&stats).Error
+	return stats, err
+}
+
+func (s *QueueStatistics) GetCustomerCancelled(filter StatisticsFilter) ([]QueueStatistics, error) {
 	var stats []QueueStatistics
 	query := database.DB.Table("QueueTickets").
-		Select("counter_id, service_id, venue_id, COUNT(*) as total_served").
-		Where("status = ? AND completed_at IS NOT NULL", "completed")
+		Select("counter_id, service_id, venue_id, COUNT(*) as customer_cancelled").
+		Where("status = ? AND cancelled_by = ?", "cancelled", "customer")
 
 	if filter.VenueID != 0 {
 		query = query.Where("venue_id = ?", filter.VenueID)
@@ -87,3 +136,51 @@ func (s *QueueStatistics) GetTotalServed(filter StatisticsFilter) ([]QueueStatis
 	err := query.Find(&stats).Error
 	return stats, err
 }
+
+// GetTransfers counts the tickets transferred into and out of each service
+func (s *QueueStatistics) GetTransfers(filter StatisticsFilter) ([]QueueStatistics, error) {
+	var transfersIn, transfersOut []QueueStatistics
+
+	inQuery := database.DB.Table("TicketTransfers").
+		Select("to_service_id AS service_id, venue_id, COUNT(*) AS transferred_in")
+	outQuery := database.DB.Table("TicketTransfers").
+		Select("from_service_id AS service_id, venue_id, COUNT(*) AS transferred_out").
+		Where("from_service_id IS NOT NULL")
+
+	if filter.VenueID != 0 {
+		inQuery = inQuery.Where("venue_id = ?", filter.VenueID)
+		outQuery = outQuery.Where("venue_id = ?", filter.VenueID)
+	}
+	if filter.ServiceID != 0 {
+		inQuery = inQuery.Where("to_service_id = ?", filter.ServiceID)
+		outQuery = outQuery.Where("from_service_id = ?", filter.ServiceID)
+	}
+	if filter.CounterID != 0 {
+		inQuery = inQuery.Where("to_counter_id = ?", filter.CounterID)
+		outQuery = outQuery.Where("from_counter_id = ?", filter.CounterID)
+	}
+
+	if err := inQuery.Group("venue_id, to_service_id").Find(&transfersIn).Error; err != nil {
+		return nil, err
+	}
+	if err := outQuery.Group("venue_id, from_service_id").Find(&transfersOut).Error; err != nil {
+		return nil, err
+	}
+
+	// Merge both directions into one row per venue and service
+	stats := transfersIn
+	index := map[[2]uint]int{}
+	for i, stat := range stats {
+		index[[2]uint{stat.VenueID, stat.ServiceID}] = i
+	}
+	for _, out := range transfersOut {
+		key := [2]uint{out.VenueID, out.ServiceID}
+		if i, ok := index[key]; ok {
+			stats[i].TransferredOut = out.TransferredOut
+			continue
+		}
+		index[key] = len(stats)
+		stats = append(stats, out)
+	}
+	return stats, nil
+}
@@ -0,0 +1,384 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"queue-system-backend/database"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Statistics series bucket sizes
+const (
+	BucketHour  = "hour"
+	BucketDay   = "day"
+	BucketWeek  = "week" // Weeks start on Monday
+	BucketMonth = "month"
+)
+
+// Statistics series groupings
+const (
+	GroupByVenue    = "venue"
+	GroupByService  = "service"
+	GroupByCounter  = "counter"
+	GroupByOperator = "operator"
+)
+
+// groupColumns are the columns of the service legs each grouping groups on
+var groupColumns = map[string]string{
+	GroupByVenue:    "venue_id",
+	GroupByService:  "service_id",
+	GroupByCounter:  "counter_id",
+	GroupByOperator: "operator_id",
+}
+
+// maxSeriesBuckets bounds the number of buckets of one series, e.g. 83 days by the hour
+const maxSeriesBuckets = 2000
+
+// maxDurationLegs bounds the called tickets loaded to compute wait and service time medians and percentiles
+const maxDurationLegs = 100000
+
+// ErrInvalidSeriesQuery is returned when a statistics series is asked with an unknown bucket or grouping
+var ErrInvalidSeriesQuery = errors.New("invalid statistics query")
+
+// SeriesQuery selects the tickets of a statistics series and how they are bucketed and grouped
+type SeriesQuery struct {
+	Filter   StatisticsFilter
+	From     time.Time
+	To       time.Time
+	Bucket   string         // BucketHour, BucketDay, BucketWeek or BucketMonth
+	GroupBy  string         // GroupByVenue, GroupByService, GroupByCounter or GroupByOperator
+	Location *time.Location // Buckets start at midnight in this time zone
+}
+
+// StatisticsSeries is the series of one venue, service, counter or operator
+type StatisticsSeries struct {
+	GroupBy string            `json:"group_by"`
+	GroupID uint              `json:"group_id"` // 0 gathers the tickets without one, e.g. never called to a counter
+	Points  []StatisticsPoint `json:"points"`
+}
+
+// StatisticsPoint holds the ticket counts and times of one bucket. Each event counts in the bucket
+// it happened in: issued by creation, served by completion, waits by call time. Tickets transferred
+// or moved on in their workflow count in the service, counter and operator of each leg.
+type StatisticsPoint struct {
+	BucketStart          time.Time `json:"bucket_start"`
+	Issued               int       `json:"issued"`
+	Served               int       `json:"served"`
+	Skipped              int       `json:"skipped"`
+	Cancelled            int       `json:"cancelled"`
+	AvgWaitMinutes       *float64  `json:"avg_wait_minutes"` // Null when no ticket was called in the bucket
+	MedianWaitMinutes    *float64  `json:"median_wait_minutes"`
+	AvgServiceMinutes    *float64  `json:"avg_service_minutes"` // Null when no ticket was completed in the bucket
+	MedianServiceMinutes *float64  `json:"median_service_minutes"`
+
+	waits    []float64
+	services []float64
+}
+
+// seriesTicket is one service leg of a ticket, the part the statistics series are computed from.
+// Waits run from joining the leg's queue; only the ticket's first leg has an IssuedAt.
+type seriesTicket struct {
+	TicketID    uint
+	VenueID     *uint
+	ServiceID   *uint
+	CounterID   *uint
+	OperatorID  *uint
+	Priority    string
+	IssuedAt    *time.Time
+	JoinedAt    time.Time
+	CalledAt    *time.Time
+	CompletedAt *time.Time
+	SkippedAt   *time.Time
+	CancelledAt *time.Time
+}
+
+// validate checks the query's bucket, grouping and period and fills in defaults
+func (q *SeriesQuery) validate() error {
+	switch q.Bucket {
+	case "":
+		q.Bucket = BucketDay
+	case BucketHour, BucketDay, BucketWeek, BucketMonth:
+	default:
+		return fmt.Errorf("%w: bucket must be 'hour', 'day', 'week' or 'month'", ErrInvalidSeriesQuery)
+	}
+	switch q.GroupBy {
+	case "":
+		q.GroupBy = GroupByVenue
+	case GroupByVenue, GroupByService, GroupByCounter, GroupByOperator:
+	default:
+		return fmt.Errorf("%w: group_by must be 'venue', 'service', 'counter' or 'operator'", ErrInvalidSeriesQuery)
+	}
+	if q.Location == nil {
+		q.Location = time.Local
+	}
+	if !q.To.After(q.From) {
+		return fmt.Errorf("%w: to must be after from", ErrInvalidSeriesQuery)
+	}
+
+	buckets := 0
+	for start := bucketStart(q.From, q.Bucket, q.Location); start.Before(q.To); start = nextBucket(start, q.Bucket) {
+		if buckets++; buckets > maxSeriesBuckets {
+			return fmt.Errorf("%w: the period spans more than %d buckets", ErrInvalidSeriesQuery, maxSeriesBuckets)
+		}
+	}
+	return nil
+}
+
+// bucketStart returns the start of the bucket containing t
+func bucketStart(t time.Time, bucket string, location *time.Location) time.Time {
+	t = t.In(location)
+	switch bucket {
+	case BucketHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, location)
+	case BucketWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, location)
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, location)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+	}
+}
+
+// nextBucket returns the start of the bucket after the one starting at start
+func nextBucket(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketHour:
+		return start.Add(time.Hour)
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// GetStatisticsSeries computes ticket counts and wait and service times per bucket of the period,
+// one series per group. Counts are grouped in SQL; only the called and completed legs are loaded
+// for the medians.
+func GetStatisticsSeries(query SeriesQuery) ([]StatisticsSeries, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	groups := map[uint]map[time.Time]*StatisticsPoint{}
+	pointAt := func(groupID uint, t time.Time) *StatisticsPoint {
+		points, ok := groups[groupID]
+		if !ok {
+			points = map[time.Time]*StatisticsPoint{}
+			groups[groupID] = points
+		}
+		start := bucketStart(t, query.Bucket, query.Location)
+		point, ok := points[start]
+		if !ok {
+			point = &StatisticsPoint{BucketStart: start}
+			points[start] = point
+		}
+		return point
+	}
+
+	counters := []struct {
+		column string
+		add    func(point *StatisticsPoint, events int)
+	}{
+		{"issued_at", func(point *StatisticsPoint, events int) { point.Issued += events }},
+		{"completed_at", func(point *StatisticsPoint, events int) { point.Served += events }},
+		{"skipped_at", func(point *StatisticsPoint, events int) { point.Skipped += events }},
+		{"cancelled_at", func(point *StatisticsPoint, events int) { point.Cancelled += events }},
+	}
+	for _, counter := range counters {
+		counts, err := countLegEvents(query.Filter, groupColumns[query.GroupBy], counter.column, query.From, query.To)
+		if err != nil {
+			return nil, err
+		}
+		for _, count := range counts {
+			counter.add(pointAt(count.GroupID, count.At), count.Events)
+		}
+	}
+
+	legs, err := loadDurationLegs(query.Filter, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	inPeriod := func(t *time.Time) bool {
+		return t != nil && !t.Before(query.From) && t.Before(query.To)
+	}
+	for i := range legs {
+		leg := &legs[i]
+		groupID := leg.groupID(query.GroupBy)
+		if inPeriod(leg.CalledAt) {
+			point := pointAt(groupID, *leg.CalledAt)
+			point.waits = append(point.waits, leg.CalledAt.Sub(leg.JoinedAt).Minutes())
+		}
+		if inPeriod(leg.CompletedAt) && leg.CalledAt != nil {
+			point := pointAt(groupID, *leg.CompletedAt)
+			point.services = append(point.services, leg.CompletedAt.Sub(*leg.CalledAt).Minutes())
+		}
+	}
+
+	groupIDs := make([]uint, 0, len(groups))
+	for groupID := range groups {
+		groupIDs = append(groupIDs, groupID)
+	}
+	sort.Slice(groupIDs, func(i, j int) bool { return groupIDs[i] < groupIDs[j] })
+
+	series := make([]StatisticsSeries, 0, len(groupIDs))
+	for _, groupID := range groupIDs {
+		// Every bucket of the period is listed so charts get a continuous axis
+		points := []StatisticsPoint{}
+		for start := bucketStart(query.From, query.Bucket, query.Location); start.Before(query.To); start = nextBucket(start, query.Bucket) {
+			point, ok := groups[groupID][start]
+			if !ok {
+				points = append(points, StatisticsPoint{BucketStart: start})
+				continue
+			}
+			point.AvgWaitMinutes, point.MedianWaitMinutes = mean(point.waits), median(point.waits)
+			point.AvgServiceMinutes, point.MedianServiceMinutes = mean(point.services), median(point.services)
+			points = append(points, *point)
+		}
+		series = append(series, StatisticsSeries{GroupBy: query.GroupBy, GroupID: groupID, Points: points})
+	}
+	return series, nil
+}
+
+// loadSeriesTickets loads the service legs with any lifecycle event within the period, see serviceLegs
+func loadSeriesTickets(filter StatisticsFilter, from time.Time, to time.Time) ([]seriesTicket, error) {
+	query := serviceLegs(func(query *gorm.DB) *gorm.DB {
+		query = query.Where("(joined_at >= ? AND joined_at < ?) OR (called_at >= ? AND called_at < ?) OR "+
+			"(completed_at >= ? AND completed_at < ?) OR (skipped_at >= ? AND skipped_at < ?) OR "+
+			"(cancelled_at >= ? AND cancelled_at < ?)",
+			from, to, from, to, from, to, from, to, from, to)
+		return filter.apply(query)
+	})
+
+	var tickets []seriesTicket
+	err := query.Scan(&tickets).Error
+	return tickets, err
+}
+
+// loadDurationLegs loads the service legs called or completed within the period, the ones wait and
+// service times are measured on. More than maxDurationLegs is refused rather than loaded.
+func loadDurationLegs(filter StatisticsFilter, from time.Time, to time.Time) ([]seriesTicket, error) {
+	query := serviceLegs(func(query *gorm.DB) *gorm.DB {
+		query = query.Where("(called_at >= ? AND called_at < ?) OR (completed_at >= ? AND completed_at < ?)",
+			from, to, from, to)
+		return filter.apply(query)
+	})
+
+	var legs []seriesTicket
+	if err := query.Limit(maxDurationLegs + 1).Scan(&legs).Error; err != nil {
+		return nil, err
+	}
+	if len(legs) > maxDurationLegs {
+		return nil, fmt.Errorf("%w: more than %d tickets were called in the period, choose a shorter one",
+			ErrInvalidSeriesQuery, maxDurationLegs)
+	}
+	return legs, nil
+}
+
+// legEventCount is the number of legs whose event happened in one quarter hour, for one group
+type legEventCount struct {
+	GroupID uint
+	At      time.Time // Start of the quarter hour
+	Events  int
+}
+
+// countLegEvents counts the service legs whose column falls within the period per group and quarter
+// hour. Bucket boundaries in every time zone fall on a quarter hour, so the counts add up per bucket.
+func countLegEvents(filter StatisticsFilter, groupColumn string, column string, from time.Time, to time.Time) ([]legEventCount, error) {
+	origin := from.Truncate(15 * time.Minute)
+	query := serviceLegs(func(query *gorm.DB) *gorm.DB {
+		return filter.apply(query.Where(column+" >= ? AND "+column+" < ?", from, to))
+	})
+
+	var rows []struct {
+		GroupID *uint
+		Slot    int64
+		Events  int
+	}
+	err := query.Select(groupColumn+" AS group_id, "+quarterHoursSinceSQL(database.DB, column)+" AS slot, COUNT(*) AS events", origin).
+		Group("group_id, slot").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]legEventCount, len(rows))
+	for i, row := range rows {
+		counts[i] = legEventCount{At: origin.Add(time.Duration(row.Slot) * 15 * time.Minute), Events: row.Events}
+		if row.GroupID != nil {
+			counts[i].GroupID = *row.GroupID
+		}
+	}
+	return counts, nil
+}
+
+// quarterHoursSinceSQL returns an SQL expression of the whole quarter hours from the time bound to its
+// placeholder until column, for the connected database
+func quarterHoursSinceSQL(db *gorm.DB, column string) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return fmt.Sprintf("TIMESTAMPDIFF(SECOND, ?, %s) DIV 900", column)
+	case "postgres":
+		return fmt.Sprintf("FLOOR(EXTRACT(EPOCH FROM (%s - ?)) / 900)", column)
+	default:
+		return fmt.Sprintf("CAST(ROUND((julianday(%s) - julianday(?)) * 86400) AS INTEGER) / 900", column)
+	}
+}
+
+// groupID returns the ID of the group the ticket belongs to, 0 when it has none
+func (t *seriesTicket) groupID(groupBy string) uint {
+	var id *uint
+	switch groupBy {
+	case GroupByService:
+		id = t.ServiceID
+	case GroupByCounter:
+		id = t.CounterID
+	case GroupByOperator:
+		id = t.OperatorID
+	default:
+		id = t.VenueID
+	}
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// mean returns the average of values, or nil when there are none
+func mean(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	average := total / float64(len(values))
+	return &average
+}
+
+// median returns the middle of values, or nil when there are none. values is sorted in place.
+func median(values []float64) *float64 {
+	return percentile(values, 50)
+}
+
+// percentile returns the p-th percentile (0-100) of values, interpolating between the closest ranks,
+// or nil when there are none. values is sorted in place.
+func percentile(values []float64, p float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Float64s(values)
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	result := values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+	return &result
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBucketStart(t *testing.T) {
+	location := time.FixedZone("UTC+2", 2*60*60)
+	// Wednesday 23:30 UTC is already Thursday 01:30 in the location
+	wednesdayNight := time.Date(2026, 10, 14, 23, 30, 15, 0, time.UTC)
+	sunday := time.Date(2026, 11, 1, 12, 0, 0, 0, location)
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, location)
+
+	tests := []struct {
+		name   string
+		t      time.Time
+		bucket string
+		want   time.Time
+	}{
+		{"hour", wednesdayNight, BucketHour, time.Date(2026, 10, 15, 1, 0, 0, 0, location)},
+		{"day in the location", wednesdayNight, BucketDay, time.Date(2026, 10, 15, 0, 0, 0, 0, location)},
+		{"unknown bucket is a day", wednesdayNight, "", time.Date(2026, 10, 15, 0, 0, 0, 0, location)},
+		{"week starts on Monday", wednesdayNight, BucketWeek, monday},
+		{"Monday starts its own week", monday, BucketWeek, monday},
+		{"Sunday belongs to the previous Monday across months", sunday, BucketWeek, time.Date(2026, 10, 26, 0, 0, 0, 0, location)},
+		{"month", wednesdayNight, BucketMonth, time.Date(2026, 10, 1, 0, 0, 0, 0, location)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bucketStart(tt.t, tt.bucket, location); !got.Equal(tt.want) {
+				t.Errorf("bucketStart(%v, %q) = %v, want %v", tt.t, tt.bucket, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetStatisticsSeries(t *testing.T) {
+	db := openTestDB(t)
+	location := time.FixedZone("UTC+2", 2*60*60)
+	day1 := time.Date(2026, 10, 14, 0, 0, 0, 0, location)
+	day2 := day1.AddDate(0, 0, 1)
+	at := func(day time.Time, hour int, minute int) *time.Time {
+		t := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		return &t
+	}
+
+	venueID := uint(1)
+	service1, service2 := uint(1), uint(2)
+	tickets := []QueueTicket{
+		// Served on the first day
+		{ServiceID: &service1, CreatedAt: *at(day1, 9, 0), CalledAt: at(day1, 9, 10), CompletedAt: at(day1, 9, 30)},
+		// Issued before midnight, called and skipped after
+		{ServiceID: &service1, CreatedAt: *at(day1, 23, 50), CalledAt: at(day2, 0, 20), SkippedAt: at(day2, 0, 30)},
+		// Issued on the stroke of midnight and cancelled
+		{ServiceID: &service2, CreatedAt: day2, CancelledAt: at(day2, 0, 5)},
+		// Transferred from service 1 and served by service 2
+		{ServiceID: &service2, CreatedAt: *at(day2, 11, 0), JoinedAt: at(day2, 11, 30), CalledAt: at(day2, 11, 45), CompletedAt: at(day2, 12, 0)},
+	}
+	for i := range tickets {
+		tickets[i].UserID = 1
+		tickets[i].VenueID = &venueID
+		tickets[i].QueueNumber = fmt.Sprintf("A-%03d", i+1)
+		tickets[i].Token = fmt.Sprintf("token-%d", i+1)
+		tickets[i].Status = TicketStatusWaiting
+	}
+	if err := db.Create(&tickets).Error; err != nil {
+		t.Fatal(err)
+	}
+	leg := TicketLeg{TicketID: tickets[3].TicketID, VenueID: &venueID, ServiceID: &service1, IssuedAt: &tickets[3].CreatedAt,
+		JoinedAt: tickets[3].CreatedAt, EndedAt: *tickets[3].JoinedAt, EndedBy: LegEndedByTransfer}
+	if err := db.Create(&leg).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	series, err := GetStatisticsSeries(SeriesQuery{
+		Filter:   StatisticsFilter{VenueID: venueID},
+		From:     *at(day1, 0, 7),
+		To:       day2.AddDate(0, 0, 1),
+		Bucket:   BucketDay,
+		GroupBy:  GroupByService,
+		Location: location,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type point struct {
+		issued, served, skipped, cancelled int
+		wait, service                      *float64
+	}
+	want := map[uint][]point{
+		service1: {
+			{issued: 2, served: 1, wait: floatPtr(10), service: floatPtr(20)},
+			{issued: 1, skipped: 1, wait: floatPtr(30)},
+		},
+		service2: {
+			{},
+			{issued: 1, served: 1, cancelled: 1, wait: floatPtr(15), service: floatPtr(15)},
+		},
+	}
+	if len(series) != len(want) {
+		t.Fatalf("got %d series, want %d", len(series), len(want))
+	}
+	for _, s := range series {
+		points := want[s.GroupID]
+		if len(s.Points) != len(points) {
+			t.Fatalf("service %d has %d points, want %d", s.GroupID, len(s.Points), len(points))
+		}
+		for i, p := range points {
+			got := s.Points[i]
+			if !got.BucketStart.Equal(day1.AddDate(0, 0, i)) {
+				t.Errorf("service %d point %d starts at %v, want %v", s.GroupID, i, got.BucketStart, day1.AddDate(0, 0, i))
+			}
+			if got.Issued != p.issued || got.Served != p.served || got.Skipped != p.skipped || got.Cancelled != p.cancelled {
+				t.Errorf("service %d point %d counts %d/%d/%d/%d issued/served/skipped/cancelled, want %d/%d/%d/%d", s.GroupID, i,
+					got.Issued, got.Served, got.Skipped, got.Cancelled, p.issued, p.served, p.skipped, p.cancelled)
+			}
+			assertFloatPtr(t, fmt.Sprintf("service %d point %d median wait", s.GroupID, i), got.MedianWaitMinutes, p.wait, 1e-6)
+			assertFloatPtr(t, fmt.Sprintf("service %d point %d median service time", s.GroupID, i), got.MedianServiceMinutes, p.service, 1e-6)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"queue-system-backend/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TicketLeg is a service a ticket went through before it was transferred or moved on to the next
+// step of its workflow. The QueueTickets row holds the leg the ticket is currently on, so the
+// per-service statistics read both, see serviceLegs.
+type TicketLeg struct {
+	LegID       uint       `json:"leg_id" gorm:"primaryKey;autoIncrement"`
+	TicketID    uint       `json:"ticket_id" gorm:"not null;index"`
+	VenueID     *uint      `json:"venue_id" gorm:"index"`
+	ServiceID   *uint      `json:"service_id" gorm:"index"`
+	CounterID   *uint      `json:"counter_id"`
+	OperatorID  *uint      `json:"operator_id"`
+	Priority    string     `json:"priority" gorm:"size:20"`
+	IssuedAt    *time.Time `json:"issued_at"` // When the ticket was issued, on its first leg only
+	JoinedAt    time.Time  `json:"joined_at" gorm:"not null;index"`
+	CalledAt    *time.Time `json:"called_at"`
+	CompletedAt *time.Time `json:"completed_at"` // Set when a workflow step was completed
+	SkippedAt   *time.Time `json:"skipped_at"`
+	EndedAt     time.Time  `json:"ended_at" gorm:"not null"`
+	EndedBy     string     `json:"ended_by" gorm:"size:20"` // "transfer" or "workflow"
+}
+
+// TableName ensures GORM uses the correct table name
+func (TicketLeg) TableName() string {
+	return "TicketLegs"
+}
+
+// Reasons a ticket leaves a service leg
+const (
+	LegEndedByTransfer = "transfer"
+	LegEndedByWorkflow = "workflow"
+)
+
+// currentLegSQL and finishedLegSQL select the same columns from the ticket's current leg and its
+// finished ones. Only the first leg of a ticket has an issued_at.
+const (
+	currentLegSQL = "SELECT ticket_id, venue_id, service_id, counter_id, operator_id, priority, " +
+		"CASE WHEN joined_at IS NULL THEN created_at END AS issued_at, COALESCE(joined_at, created_at) AS joined_at, " +
+		"called_at, completed_at, skipped_at, cancelled_at FROM QueueTickets"
+	finishedLegSQL = "SELECT ticket_id, venue_id, service_id, counter_id, operator_id, priority, " +
+		"issued_at, joined_at, called_at, completed_at, skipped_at, NULL AS cancelled_at FROM TicketLegs"
+)
+
+// serviceLegs queries the legs of all tickets as one table named legs, each with the service it was
+// for and its own timestamps. where restricts both sources so the filters reach the table indexes.
+func serviceLegs(where func(query *gorm.DB) *gorm.DB) *gorm.DB {
+	current := where(database.DB.Table("(" + currentLegSQL + ") AS legs"))
+	finished := where(database.DB.Table("(" + finishedLegSQL + ") AS legs"))
+	return database.DB.Table("(?) AS legs", database.DB.Raw("? UNION ALL ?", current, finished))
+}
+
+// endTicketLeg records the leg a ticket is leaving within tx. The caller then moves the ticket to
+// its next service with startTicketLeg.
+func endTicketLeg(tx *gorm.DB, ticket *QueueTicket, endedBy string, now time.Time) error {
+	leg := TicketLeg{
+		TicketID:    ticket.TicketID,
+		VenueID:     ticket.VenueID,
+		ServiceID:   ticket.ServiceID,
+		CounterID:   ticket.CounterID,
+		OperatorID:  ticket.OperatorID,
+		Priority:    ticket.Priority,
+		JoinedAt:    ticket.CreatedAt,
+		CalledAt:    ticket.CalledAt,
+		CompletedAt: ticket.CompletedAt,
+		SkippedAt:   ticket.SkippedAt,
+		EndedAt:     now,
+		EndedBy:     endedBy,
+	}
+	if ticket.JoinedAt != nil {
+		leg.JoinedAt = *ticket.JoinedAt
+	} else {
+		leg.IssuedAt = &ticket.CreatedAt
+	}
+	if err := tx.Create(&leg).Error; err != nil {
+		return errors.New("failed to record ticket leg: " + err.Error())
+	}
+	return nil
+}
+
+// startTicketLeg adds to updates the columns of a ticket joining another service at now, so its row
+// only describes the new leg, and mirrors them onto the struct
+func (t *QueueTicket) startTicketLeg(updates map[string]interface{}, now time.Time) map[string]interface{} {
+	updates["joined_at"] = now
+	for _, column := range []string{"counter_id", "operator_id", "called_at", "serving_at", "completed_at", "skipped_at", "recalled_at"} {
+		updates[column] = nil
+	}
+	t.JoinedAt = &now
+	t.CounterID = nil
+	t.OperatorID = nil
+	t.CalledAt = nil
+	t.ServingAt = nil
+	t.CompletedAt = nil
+	t.SkippedAt = nil
+	t.RecalledAt = nil
+	return updates
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServiceLegs(t *testing.T) {
+	db := openTestDB(t)
+	venueID, userID := uint(1), uint(1)
+	services := []Service{
+		{ServiceID: 1, UserID: &userID, VenueID: &venueID, ServiceName: "Registration"},
+		{ServiceID: 2, UserID: &userID, VenueID: &venueID, ServiceName: "Payment"},
+	}
+	if err := db.Create(&services).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(-time.Hour)
+	queuedAt := start
+	ticket := QueueTicket{
+		UserID:      userID,
+		ServiceID:   &services[0].ServiceID,
+		VenueID:     &venueID,
+		QueueNumber: "A-001",
+		Token:       "token-1",
+		Status:      TicketStatusWaiting,
+		Priority:    PriorityRegular,
+		CreatedAt:   start,
+		QueuedAt:    &queuedAt,
+	}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	operatorID, counterID := uint(7), uint(3)
+	if err := UpdateQueueTicketStatus(ticket.TicketID, TicketStatusCalled, &operatorID, &counterID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TransferQueueTicket(ticket.TicketID, TransferTarget{ServiceID: 2}, &operatorID, &counterID); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateQueueTicketStatus(ticket.TicketID, TicketStatusCalled, &operatorID, &counterID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CompleteQueueTicket(ticket.TicketID, &operatorID, &counterID); err != nil {
+		t.Fatal(err)
+	}
+
+	legs, err := loadSeriesTickets(StatisticsFilter{VenueID: venueID}, start.Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legs) != 2 {
+		t.Fatalf("loaded %d legs, want 2", len(legs))
+	}
+	byService := map[uint]seriesTicket{}
+	for _, leg := range legs {
+		byService[*leg.ServiceID] = leg
+	}
+
+	first, second := byService[1], byService[2]
+	if first.IssuedAt == nil || !first.IssuedAt.Equal(start) || !first.JoinedAt.Equal(start) {
+		t.Errorf("first leg issued at %v and joined at %v, want both %v", first.IssuedAt, first.JoinedAt, start)
+	}
+	if first.CalledAt == nil || first.CompletedAt != nil {
+		t.Errorf("first leg called at %v and completed at %v, want called only", first.CalledAt, first.CompletedAt)
+	}
+	if second.IssuedAt != nil {
+		t.Errorf("second leg issued at %v, want only the first leg issued", second.IssuedAt)
+	}
+	if second.JoinedAt.Before(*first.CalledAt) {
+		t.Errorf("second leg joined at %v, before the first leg was called at %v", second.JoinedAt, first.CalledAt)
+	}
+	if second.CalledAt == nil || second.CompletedAt == nil {
+		t.Errorf("second leg called at %v and completed at %v, want both", second.CalledAt, second.CompletedAt)
+	}
+
+	var stats QueueStatistics
+	served, err := stats.GetTotalServed(StatisticsFilter{VenueID: venueID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(served) != 1 || served[0].ServiceID != 2 || served[0].TotalServed != 1 {
+		t.Errorf("GetTotalServed = %+v, want one ticket served by service 2", served)
+	}
+}
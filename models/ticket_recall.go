@@ -0,0 +1,285 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"queue-system-backend/database"
+	"queue-system-backend/events"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rejoin policies for skipped tickets whose customer reappears
+const (
+	// RejoinNone doesn't let skipped tickets back into the queue
+	RejoinNone = "none"
+	// RejoinEnd puts a skipped ticket at the end of its priority class
+	RejoinEnd = "end"
+	// RejoinOriginal puts a skipped ticket back at the position it was issued at
+	RejoinOriginal = "original"
+)
+
+// ErrRejoinNotAllowed is returned when the service doesn't let skipped tickets rejoin the queue
+var ErrRejoinNotAllowed = errors.New("skipped tickets cannot rejoin this queue")
+
+// maxCustomerRejoins is how many times a customer may put their skipped ticket back in the queue themselves
+const maxCustomerRejoins = 1
+
+// validateNoShowPolicy checks the service's no-show and rejoin settings and fills in defaults
+func (s *Service) validateNoShowPolicy() error {
+	if s.NoShowGraceSeconds < 0 {
+		return errors.New("no_show_grace_seconds cannot be negative")
+	}
+	if s.RejoinPolicy == "" {
+		s.RejoinPolicy = RejoinNone
+	}
+	if s.RejoinPolicy != RejoinNone && s.RejoinPolicy != RejoinEnd && s.RejoinPolicy != RejoinOriginal {
+		return errors.New("rejoin_policy must be 'none', 'end' or 'original'")
+	}
+	return nil
+}
+
+// RecallQueueTicket re-announces a called ticket on the displays and counts the recall.
+// Skipped tickets are called back instead, moving them to recalled.
+func RecallQueueTicket(ticketID uint, operatorID *uint, counterID *uint, reason string) (*QueueTicket, error) {
+	var ticket *QueueTicket
+	reannounced := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ticket, err = lockQueueTicket(tx, ticketID, 0, true)
+		if err != nil {
+			return err
+		}
+
+		actor := TicketActor{OperatorID: operatorID, CounterID: counterID, Reason: reason}
+		if ticket.Status != TicketStatusCalled && ticket.Status != TicketStatusRecalled {
+			return transitionQueueTicket(tx, ticket, TicketStatusRecalled, actor)
+		}
+
+		now := time.Now()
+		err = tx.Model(&QueueTicket{}).
+			Where("ticket_id = ?", ticket.TicketID).
+			Updates(map[string]interface{}{
+				"recall_count": gorm.Expr("recall_count + 1"),
+				"announced_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		ticket.RecallCount++
+		ticket.AnnouncedAt = &now
+		reannounced = true
+
+		if reason == "" {
+			reason = fmt.Sprintf("recall %d", ticket.RecallCount)
+		}
+		event := QueueTicketEvent{
+			TicketID:   ticket.TicketID,
+			FromStatus: ticket.Status,
+			ToStatus:   ticket.Status,
+			OperatorID: operatorID,
+			CounterID:  counterID,
+			Reason:     reason,
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return errors.New("failed to record ticket event: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reannounced {
+		publishTicket(events.TypeTicketReannounced, ticket)
+	} else {
+		publishTicketEvent(ticket)
+	}
+	return ticket, nil
+}
+
+// SkipNoShowTickets skips the called tickets whose customer didn't come to the counter within
+// their service's NoShowGraceSeconds of the last announcement, and returns how many were skipped
+func SkipNoShowTickets(now time.Time) (int, error) {
+	var services []Service
+	if err := database.DB.Where("no_show_grace_seconds > 0").Find(&services).Error; err != nil {
+		return 0, err
+	}
+
+	skipped := 0
+	for _, service := range services {
+		deadline := now.Add(-time.Duration(service.NoShowGraceSeconds) * time.Second)
+
+		var ticketIDs []uint
+		err := database.DB.Model(&QueueTicket{}).
+			Where("service_id = ? AND status IN ? AND announced_at <= ?", service.ServiceID,
+				[]string{TicketStatusCalled, TicketStatusRecalled}, deadline).
+			Pluck("ticket_id", &ticketIDs).Error
+		if err != nil {
+			return skipped, err
+		}
+
+		for _, ticketID := range ticketIDs {
+			var ticket QueueTicket
+			err := database.DB.Transaction(func(tx *gorm.DB) error {
+				// Re-check under the lock: the customer may have shown up or been recalled meanwhile
+				err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					Where("ticket_id = ? AND status IN ? AND announced_at <= ?", ticketID,
+						[]string{TicketStatusCalled, TicketStatusRecalled}, deadline).
+					First(&ticket).Error
+				if err != nil {
+					return err
+				}
+				return transitionQueueTicket(tx, &ticket, TicketStatusSkipped, TicketActor{
+					Reason: fmt.Sprintf("no-show after %d seconds", service.NoShowGraceSeconds),
+				})
+			})
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return skipped, err
+			}
+
+			publishTicketEvent(&ticket)
+			skipped++
+		}
+	}
+	return skipped, nil
+}
+
+// RunNoShowAutoSkip skips no-show tickets every interval. It blocks, so run it in its own goroutine.
+func RunNoShowAutoSkip(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if skipped, err := SkipNoShowTickets(time.Now()); err != nil {
+			log.Printf("🔴 Failed to skip no-show tickets: %v", err)
+		} else if skipped > 0 {
+			log.Printf("🟢 Skipped %d no-show ticket(s)", skipped)
+		}
+	}
+}
+
+// RejoinQueueTicket puts a skipped ticket back into its queue when the customer reappears,
+// at the end or at its original position depending on the service's RejoinPolicy
+func RejoinQueueTicket(ticketID uint, operatorID *uint, counterID *uint) (*QueueTicket, error) {
+	return rejoinQueueTicket(func(tx *gorm.DB) (*QueueTicket, error) {
+		return lockQueueTicket(tx, ticketID, 0, true)
+	}, TicketActor{OperatorID: operatorID, CounterID: counterID})
+}
+
+// RejoinQueueTicketByToken lets a customer whose ticket was skipped rejoin the queue themselves
+func RejoinQueueTicketByToken(token string) (*QueueTicket, error) {
+	return rejoinQueueTicket(func(tx *gorm.DB) (*QueueTicket, error) {
+		return lockQueueTicketByToken(tx, token)
+	}, TicketActor{ByCustomer: true})
+}
+
+// rejoinQueueTicket moves the ticket loaded by lock from skipped back to waiting. Tickets of an earlier
+// business day stay skipped, and customers only get maxCustomerRejoins tries on their own.
+func rejoinQueueTicket(lock func(tx *gorm.DB) (*QueueTicket, error), actor TicketActor) (*QueueTicket, error) {
+	var ticket *QueueTicket
+	var displays []QueueDisplay
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ticket, err = lock(tx)
+		if err != nil {
+			return err
+		}
+		if ticket.Status != TicketStatusSkipped {
+			return fmt.Errorf("%w: only skipped tickets can rejoin the queue", ErrInvalidTransition)
+		}
+		if ticket.VenueID == nil || ticket.ServiceID == nil {
+			return errors.New("ticket is not in a service queue")
+		}
+
+		if actor.ByCustomer && ticket.CustomerRejoins >= maxCustomerRejoins {
+			return fmt.Errorf("%w: the ticket already rejoined the queue", ErrRejoinNotAllowed)
+		}
+
+		var service Service
+		if err := tx.First(&service, *ticket.ServiceID).Error; err != nil {
+			return errors.New("service not found")
+		}
+
+		// Only tickets of the current business day rejoin, and only while the venue takes tickets
+		now := time.Now()
+		venue, err := GetVenueByID(*ticket.VenueID)
+		if err != nil {
+			return err
+		}
+		dayStart, dayEnd, err := venue.BusinessDayRange(venue.BusinessDate(now))
+		if err != nil {
+			return err
+		}
+		if ticket.CreatedAt.Before(dayStart) || !ticket.CreatedAt.Before(dayEnd) {
+			return fmt.Errorf("%w: the ticket was issued on another business day", ErrRejoinNotAllowed)
+		}
+		if err := venue.CheckTicketIssuance(now); err != nil {
+			return err
+		}
+
+		queuedAt := now
+		switch service.RejoinPolicy {
+		case RejoinEnd:
+		case RejoinOriginal:
+			if ticket.QueuedAt != nil {
+				queuedAt = *ticket.QueuedAt
+			}
+		default:
+			return ErrRejoinNotAllowed
+		}
+
+		updates := map[string]interface{}{
+			"status":     TicketStatusWaiting,
+			"counter_id": nil,
+			"queued_at":  queuedAt,
+			"at_head":    false,
+		}
+		if actor.ByCustomer {
+			updates["customer_rejoins"] = gorm.Expr("customer_rejoins + 1")
+			ticket.CustomerRejoins++
+		}
+		if err := tx.Model(&QueueTicket{}).Where("ticket_id = ?", ticket.TicketID).Updates(updates).Error; err != nil {
+			return err
+		}
+		ticket.Status = TicketStatusWaiting
+		ticket.CounterID = nil
+		ticket.QueuedAt = &queuedAt
+		ticket.AtHead = false
+
+		reason := "rejoined at the end of the queue"
+		if service.RejoinPolicy == RejoinOriginal {
+			reason = "rejoined at the original position"
+		}
+		if actor.ByCustomer {
+			reason += " by customer"
+		}
+		event := QueueTicketEvent{
+			TicketID:   ticket.TicketID,
+			FromStatus: TicketStatusSkipped,
+			ToStatus:   TicketStatusWaiting,
+			OperatorID: actor.OperatorID,
+			CounterID:  actor.CounterID,
+			Reason:     reason,
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return errors.New("failed to record ticket event: " + err.Error())
+		}
+
+		displays, err = refreshNextTickets(tx, *ticket.VenueID, *ticket.ServiceID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishTicketEvent(ticket)
+	publishDisplayEvents(displays)
+	return ticket, nil
+}
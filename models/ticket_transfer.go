@@ -0,0 +1,259 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"queue-system-backend/database"
+	"queue-system-backend/events"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Positions a transferred ticket takes in the target queue
+const (
+	// TransferKeepPriority joins the end of the ticket's own priority class in the target queue
+	TransferKeepPriority = "keep_priority"
+	// TransferHead places the ticket ahead of every waiting ticket of the target queue
+	TransferHead = "head"
+)
+
+// ErrInvalidTransfer is returned when a transfer target is missing or doesn't fit the ticket
+var ErrInvalidTransfer = errors.New("invalid transfer")
+
+// TicketTransfer records a ticket moving from one service queue to another
+type TicketTransfer struct {
+	TransferID    uint      `json:"transfer_id" gorm:"primaryKey;autoIncrement"`
+	TicketID      uint      `json:"ticket_id" gorm:"not null;index"`
+	VenueID       *uint     `json:"venue_id" gorm:"index"`
+	FromServiceID *uint     `json:"from_service_id" gorm:"index"`
+	ToServiceID   uint      `json:"to_service_id" gorm:"not null;index"`
+	FromCounterID *uint     `json:"from_counter_id"`
+	ToCounterID   *uint     `json:"to_counter_id"` // Counter the ticket was reserved for, if any
+	OperatorID    *uint     `json:"operator_id"`   // User who made the transfer
+	Position      string    `json:"position" gorm:"size:20"`
+	Reason        string    `json:"reason" gorm:"size:255"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName ensures GORM uses the correct table name
+func (TicketTransfer) TableName() string {
+	return "TicketTransfers"
+}
+
+// TransferTarget describes where a ticket is transferred to. A counter or operator
+// reserves the ticket for that counter; the service defaults to the counter's.
+type TransferTarget struct {
+	ServiceID  uint   `json:"to_service_id"`
+	CounterID  uint   `json:"to_counter_id"`
+	OperatorID uint   `json:"to_operator_id"`
+	Position   string `json:"position"` // keep_priority (default) or head
+	Reason     string `json:"reason"`
+}
+
+// transferableStatuses are the statuses a ticket can be transferred from
+var transferableStatuses = map[string]bool{
+	TicketStatusWaiting:  true,
+	TicketStatusCalled:   true,
+	TicketStatusServing:  true,
+	TicketStatusRecalled: true,
+}
+
+// TransferQueueTicket moves a ticket into the waiting queue of another service, or reserves it
+// for a specific counter or operator, keeping its queue number and token.
+// The transfer is recorded in the ticket history and in TicketTransfers, and the service the
+// ticket leaves in TicketLegs.
+func TransferQueueTicket(ticketID uint, target TransferTarget, operatorID *uint, counterID *uint) (*QueueTicket, error) {
+	if target.Position == "" {
+		target.Position = TransferKeepPriority
+	}
+	if target.Position != TransferKeepPriority && target.Position != TransferHead {
+		return nil, fmt.Errorf("%w: position must be 'keep_priority' or 'head'", ErrInvalidTransfer)
+	}
+
+	var ticket *QueueTicket
+	var displays []QueueDisplay
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ticket, err = lockQueueTicket(tx, ticketID, 0, true)
+		if err != nil {
+			return err
+		}
+		if !transferableStatuses[ticket.Status] {
+			return fmt.Errorf("%w: cannot transfer a %s ticket", ErrInvalidTransition, ticket.Status)
+		}
+		if ticket.VenueID == nil {
+			return fmt.Errorf("%w: ticket is not in a service queue", ErrInvalidTransfer)
+		}
+
+		toCounter, err := resolveTransferCounter(tx, target)
+		if err != nil {
+			return err
+		}
+		if toCounter != nil {
+			if toCounter.VenueID == nil || *toCounter.VenueID != *ticket.VenueID {
+				return fmt.Errorf("%w: counter does not belong to the ticket's venue", ErrInvalidTransfer)
+			}
+			if target.ServiceID == 0 && toCounter.ServiceID != nil {
+				target.ServiceID = *toCounter.ServiceID
+			}
+		}
+		if target.ServiceID == 0 {
+			return fmt.Errorf("%w: to_service_id, to_counter_id or to_operator_id is required", ErrInvalidTransfer)
+		}
+
+		var service Service
+		if err := tx.First(&service, target.ServiceID).Error; err != nil {
+			return errors.New("service not found")
+		}
+		if service.VenueID == nil || *service.VenueID != *ticket.VenueID {
+			return fmt.Errorf("%w: service does not belong to the ticket's venue", ErrInvalidTransfer)
+		}
+		if toCounter != nil {
+			if err := checkCounterServes(tx, toCounter, service.ServiceID); err != nil {
+				return err
+			}
+		}
+		if toCounter == nil && ticket.Status == TicketStatusWaiting && ticket.ServiceID != nil && *ticket.ServiceID == service.ServiceID {
+			return fmt.Errorf("%w: ticket is already waiting for this service", ErrInvalidTransfer)
+		}
+
+		// Keeping the priority joins the back of the class; the head keeps the time already waited
+		// and is called before every class
+		now := time.Now()
+		queuedAt := now
+		if target.Position == TransferHead && ticket.QueuedAt != nil {
+			queuedAt = *ticket.QueuedAt
+		}
+		var toCounterID *uint
+		if toCounter != nil {
+			toCounterID = &toCounter.CounterID
+		}
+
+		fromServiceID := ticket.ServiceID
+		fromCounterID := ticket.CounterID
+		fromStatus := ticket.Status
+		if err := endTicketLeg(tx, ticket, LegEndedByTransfer, now); err != nil {
+			return err
+		}
+		updates := ticket.startTicketLeg(map[string]interface{}{
+			"status":              TicketStatusWaiting,
+			"service_id":          service.ServiceID,
+			"assigned_counter_id": toCounterID,
+			"queued_at":           queuedAt,
+			"at_head":             target.Position == TransferHead,
+		}, now)
+		if err := tx.Model(&QueueTicket{}).Where("ticket_id = ?", ticket.TicketID).Updates(updates).Error; err != nil {
+			return err
+		}
+		ticket.Status = TicketStatusWaiting
+		ticket.ServiceID = &service.ServiceID
+		ticket.AssignedCounterID = toCounterID
+		ticket.QueuedAt = &queuedAt
+		ticket.AtHead = target.Position == TransferHead
+
+		reason := fmt.Sprintf("transferred to %s", service.ServiceName)
+		if toCounter != nil {
+			reason += fmt.Sprintf(" at %s", toCounter.CounterName)
+		}
+		if target.Reason != "" {
+			reason += ": " + target.Reason
+		}
+		event := QueueTicketEvent{
+			TicketID:   ticket.TicketID,
+			FromStatus: fromStatus,
+			ToStatus:   TicketStatusWaiting,
+			OperatorID: operatorID,
+			CounterID:  counterID,
+			Reason:     reason,
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return errors.New("failed to record ticket event: " + err.Error())
+		}
+
+		transfer := TicketTransfer{
+			TicketID:      ticket.TicketID,
+			VenueID:       ticket.VenueID,
+			FromServiceID: fromServiceID,
+			ToServiceID:   service.ServiceID,
+			FromCounterID: fromCounterID,
+			ToCounterID:   toCounterID,
+			OperatorID:    operatorID,
+			Position:      target.Position,
+			Reason:        target.Reason,
+		}
+		if err := tx.Create(&transfer).Error; err != nil {
+			return errors.New("failed to record ticket transfer: " + err.Error())
+		}
+
+		displays, err = refreshNextTickets(tx, *ticket.VenueID, service.ServiceID)
+		if err != nil {
+			return err
+		}
+		if fromStatus == TicketStatusWaiting && fromServiceID != nil && *fromServiceID != service.ServiceID {
+			previous, err := refreshNextTickets(tx, *ticket.VenueID, *fromServiceID)
+			if err != nil {
+				return err
+			}
+			displays = append(displays, previous...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishTicket(events.TypeTicketTransferred, ticket)
+	publishDisplayEvents(displays)
+	return ticket, nil
+}
+
+// resolveTransferCounter loads the counter a transfer targets, directly or through the operator working it
+func resolveTransferCounter(tx *gorm.DB, target TransferTarget) (*Counter, error) {
+	counterID := target.CounterID
+	if target.OperatorID != 0 {
+		operatorCounterID, err := GetCounterIDByUserID(int(target.OperatorID))
+		if err != nil {
+			return nil, fmt.Errorf("%w: operator is not assigned to a counter", ErrInvalidTransfer)
+		}
+		if counterID != 0 && counterID != uint(operatorCounterID) {
+			return nil, fmt.Errorf("%w: operator is not assigned to this counter", ErrInvalidTransfer)
+		}
+		counterID = uint(operatorCounterID)
+	}
+	if counterID == 0 {
+		return nil, nil
+	}
+
+	var counter Counter
+	if err := tx.First(&counter, counterID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("counter not found")
+		}
+		return nil, err
+	}
+	return &counter, nil
+}
+
+// checkCounterServes returns an error unless the counter is skilled for the service
+func checkCounterServes(tx *gorm.DB, counter *Counter, serviceID uint) error {
+	skills, err := counterSkills(tx, counter)
+	if err != nil {
+		return err
+	}
+	for _, skill := range skills {
+		if skill.ServiceID == serviceID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: counter does not serve this service", ErrInvalidTransfer)
+}
+
+// GetTicketTransfers retrieves the transfers of a ticket in chronological order
+func GetTicketTransfers(ticketID uint) ([]TicketTransfer, error) {
+	var transfers []TicketTransfer
+	err := database.DB.Where("ticket_id = ?", ticketID).
+		Order("created_at ASC, transfer_id ASC").
+		Find(&transfers).Error
+	return transfers, err
+}
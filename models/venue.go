@@ -3,22 +3,70 @@ package models
 import (
 	"errors"
 	"queue-system-backend/database"
+	"sync"
+	"time"
+	_ "time/tzdata" // Venue time zones must resolve on hosts without a zoneinfo database
 
 	"gorm.io/gorm"
 )
 
 type Venue struct {
-	VenueID    uint   `json:"venue_id" gorm:"primaryKey;autoIncrement"`
-	UserID     uint   `json:"user_id" gorm:"not null"` // Foreign key to Users table
-	VenueName  string `json:"venue_name" gorm:"size:255;not null"`
-	Address    string `json:"address" gorm:"size:255;not null"`
-	City       string `json:"city" gorm:"size:100;not null"`
-	Province   string `json:"province" gorm:"size:100;not null"`
-	PostalCode string `json:"postal_code" gorm:"size:10;not null"`
-	Phone      string `json:"phone" gorm:"size:20"`
-	Email      string `json:"email" gorm:"size:100"`
-	OpenTime   string `json:"open_time" gorm:"type:time"`
-	CloseTime  string `json:"close_time" gorm:"type:time"`
+	VenueID      uint   `json:"venue_id" gorm:"primaryKey;autoIncrement"`
+	UserID       uint   `json:"user_id" gorm:"not null"` // Foreign key to Users table
+	VenueName    string `json:"venue_name" gorm:"size:255;not null"`
+	Address      string `json:"address" gorm:"size:255;not null"`
+	City         string `json:"city" gorm:"size:100;not null"`
+	Province     string `json:"province" gorm:"size:100;not null"`
+	PostalCode   string `json:"postal_code" gorm:"size:10;not null"`
+	Phone        string `json:"phone" gorm:"size:20"`
+	Email        string `json:"email" gorm:"size:100"`
+	OpenTime     string `json:"open_time" gorm:"type:time"`
+	CloseTime    string `json:"close_time" gorm:"type:time"`
+	DayResetTime string `json:"day_reset_time" gorm:"type:time;default:null"` // Start of the business day; queue numbers restart here
+	// Minutes before CloseTime after which no new tickets are issued; 0 issues until closing
+	LastTicketCutoffMinutes int `json:"last_ticket_cutoff_minutes" gorm:"default:0"`
+	// IANA time zone the venue's hours, business days and daily statistics are evaluated in,
+	// e.g. "Asia/Jakarta" (WIB), "Asia/Makassar" (WITA) or "Asia/Jayapura" (WIT). Empty uses the server's.
+	Timezone string `json:"timezone" gorm:"size:64;default:null"`
+}
+
+// locations caches loaded time zones by name
+var locations sync.Map
+
+// loadLocation loads an IANA time zone, caching the result
+func loadLocation(name string) (*time.Location, error) {
+	if cached, ok := locations.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, location)
+	return location, nil
+}
+
+// Location returns the venue's time zone, or the server's when it has none
+func (v *Venue) Location() *time.Location {
+	if v.Timezone != "" {
+		if location, err := loadLocation(v.Timezone); err == nil {
+			return location
+		}
+	}
+	return time.Local
+}
+
+// validate checks the venue's settings
+func (v *Venue) validate() error {
+	if v.LastTicketCutoffMinutes < 0 {
+		return errors.New("last_ticket_cutoff_minutes cannot be negative")
+	}
+	if v.Timezone != "" {
+		if _, err := loadLocation(v.Timezone); err != nil {
+			return errors.New("invalid timezone, expected an IANA name such as Asia/Jakarta")
+		}
+	}
+	return nil
 }
 
 // TableName ensures GORM uses the correct table name
@@ -48,6 +96,9 @@ func (v *Venue) CreateVenue() error {
 	if v.UserID == 0 || v.VenueName == "" {
 		return errors.New("user_id and venue_name are required")
 	}
+	if err := v.validate(); err != nil {
+		return err
+	}
 
 	if err := database.DB.Create(v).Error; err != nil {
 		return err
@@ -60,6 +111,9 @@ func (v *Venue) UpdateVenue() error {
 	if database.DB == nil {
 		return errors.New("database connection is not initialized")
 	}
+	if err := v.validate(); err != nil {
+		return err
+	}
 
 	if err := database.DB.Save(v).Error; err != nil {
 		return err
@@ -97,6 +151,35 @@ func GetVenuesByUser(userID uint) ([]Venue, error) {
 	return venues, nil
 }
 
+// BusinessDate returns the venue's business day (YYYY-MM-DD) for the given time, in the venue's time zone.
+// Times before DayResetTime still belong to the previous business day.
+func (v *Venue) BusinessDate(t time.Time) string {
+	t = t.In(v.Location())
+	if v.DayResetTime != "" {
+		if reset, err := time.Parse(clockLayout, v.DayResetTime); err == nil {
+			resetAt := time.Date(t.Year(), t.Month(), t.Day(), reset.Hour(), reset.Minute(), reset.Second(), 0, t.Location())
+			if t.Before(resetAt) {
+				t = t.AddDate(0, 0, -1)
+			}
+		}
+	}
+	return t.Format(dateLayout)
+}
+
+// BusinessDayRange returns when the business day (YYYY-MM-DD) starts and ends,
+// from DayResetTime (midnight by default) in the venue's time zone to the next day's
+func (v *Venue) BusinessDayRange(businessDate string) (time.Time, time.Time, error) {
+	day, err := time.ParseInLocation(dateLayout, businessDate, v.Location())
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid date format, expected YYYY-MM-DD")
+	}
+	start := day
+	if reset, err := time.Parse(clockLayout, v.DayResetTime); err == nil {
+		start = atClock(day, reset)
+	}
+	return start, start.AddDate(0, 0, 1), nil
+}
+
 func GetVenueNameByID(venueID uint) (string, error) {
 	var venue Venue
 	if err := database.DB.Select("venue_name").First(&venue, venueID).Error; err != nil {
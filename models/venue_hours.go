@@ -0,0 +1,246 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"queue-system-backend/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// clockLayout is the layout of the TIME columns holding opening hours
+const clockLayout = "15:04:05"
+
+// dateLayout is the layout of DATE columns and business dates
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day (YYYY-MM-DD) stored in a DATE column. Drivers scanning DATE columns into
+// time.Time, as MySQL does with parseTime=True, would otherwise turn it into an RFC 3339 timestamp.
+type Date string
+
+// Scan implements sql.Scanner
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = ""
+	case time.Time:
+		*d = Date(v.Format(dateLayout))
+	case []byte:
+		*d = Date(truncateDate(string(v)))
+	case string:
+		*d = Date(truncateDate(v))
+	default:
+		return fmt.Errorf("cannot scan %T into a date", value)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (d Date) Value() (driver.Value, error) {
+	if d == "" {
+		return nil, nil
+	}
+	return string(d), nil
+}
+
+// truncateDate keeps the date of a textual timestamp, e.g. "2026-12-25T00:00:00Z"
+func truncateDate(value string) string {
+	if len(value) > len(dateLayout) {
+		return value[:len(dateLayout)]
+	}
+	return value
+}
+
+var (
+	// ErrVenueClosed is returned when tickets are issued on a day the venue's calendar marks as closed
+	ErrVenueClosed = errors.New("venue is closed today")
+	// ErrOutsideOpeningHours is returned when tickets are issued outside the venue's opening hours
+	ErrOutsideOpeningHours = errors.New("venue is outside its opening hours")
+	// ErrLastTicketCutoff is returned when tickets are issued after the venue's last ticket time
+	ErrLastTicketCutoff = errors.New("the last ticket of the day has already been issued")
+	// ErrNoCounterScheduled is returned when no counter serving the service is scheduled to be open
+	ErrNoCounterScheduled = errors.New("no counter serves this service at this time")
+)
+
+// VenueClosure is a holiday or other closure of a venue, covering whole days
+type VenueClosure struct {
+	ClosureID uint      `json:"closure_id" gorm:"primaryKey;autoIncrement"`
+	VenueID   uint      `json:"venue_id" gorm:"not null;index"`
+	StartDate Date      `json:"start_date" gorm:"type:date;not null"` // YYYY-MM-DD, inclusive
+	EndDate   Date      `json:"end_date" gorm:"type:date;not null"`   // YYYY-MM-DD, inclusive
+	Reason    string    `json:"reason" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName ensures GORM uses the correct table name
+func (VenueClosure) TableName() string {
+	return "VenueClosures"
+}
+
+// validate checks the closure's dates
+func (c *VenueClosure) validate() error {
+	start, err := time.Parse(dateLayout, string(c.StartDate))
+	if err != nil {
+		return errors.New("invalid start_date format, expected YYYY-MM-DD")
+	}
+	if c.EndDate == "" {
+		c.EndDate = c.StartDate
+	}
+	end, err := time.Parse(dateLayout, string(c.EndDate))
+	if err != nil {
+		return errors.New("invalid end_date format, expected YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return errors.New("end_date cannot be before start_date")
+	}
+	return nil
+}
+
+// CreateVenueClosure adds a closure to a venue's calendar
+func CreateVenueClosure(closure *VenueClosure) error {
+	if err := closure.validate(); err != nil {
+		return err
+	}
+	return database.DB.Create(closure).Error
+}
+
+// GetVenueClosures retrieves a venue's closures ending on or after the given date, in date order
+func GetVenueClosures(venueID uint, fromDate string) ([]VenueClosure, error) {
+	var closures []VenueClosure
+	query := database.DB.Where("venue_id = ?", venueID)
+	if fromDate != "" {
+		query = query.Where("end_date >= ?", fromDate)
+	}
+	err := query.Order("start_date ASC").Find(&closures).Error
+	return closures, err
+}
+
+// GetVenueClosureByID retrieves a closure of a venue by ID
+func GetVenueClosureByID(venueID uint, closureID uint) (*VenueClosure, error) {
+	var closure VenueClosure
+	err := database.DB.Where("venue_id = ? AND closure_id = ?", venueID, closureID).First(&closure).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("closure not found")
+		}
+		return nil, err
+	}
+	return &closure, nil
+}
+
+// UpdateVenueClosure saves changes to a closure
+func UpdateVenueClosure(closure *VenueClosure) error {
+	if err := closure.validate(); err != nil {
+		return err
+	}
+	return database.DB.Save(closure).Error
+}
+
+// DeleteVenueClosure deletes a closure of a venue
+func DeleteVenueClosure(venueID uint, closureID uint) error {
+	return database.DB.Where("venue_id = ? AND closure_id = ?", venueID, closureID).Delete(&VenueClosure{}).Error
+}
+
+// IsClosedOn reports whether the venue's calendar marks the business date as closed
+func (v *Venue) IsClosedOn(businessDate string) (bool, error) {
+	var count int64
+	err := database.DB.Model(&VenueClosure{}).
+		Where("venue_id = ? AND start_date <= ? AND end_date >= ?", v.VenueID, businessDate, businessDate).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CheckTicketIssuance returns an error when the venue doesn't accept new tickets at the given time:
+// on closure days, outside OpenTime/CloseTime and within LastTicketCutoffMinutes of closing.
+// Hours are evaluated in the venue's time zone; venues without opening hours accept tickets at any time.
+func (v *Venue) CheckTicketIssuance(now time.Time) error {
+	now = now.In(v.Location())
+
+	closed, err := v.IsClosedOn(v.BusinessDate(now))
+	if err != nil {
+		return err
+	}
+	if closed {
+		return ErrVenueClosed
+	}
+
+	closeAt, open := openingPeriodAt(v.OpenTime, v.CloseTime, now)
+	if !open {
+		return ErrOutsideOpeningHours
+	}
+	if closeAt != nil && v.LastTicketCutoffMinutes > 0 {
+		cutoff := closeAt.Add(-time.Duration(v.LastTicketCutoffMinutes) * time.Minute)
+		if !now.Before(cutoff) {
+			return ErrLastTicketCutoff
+		}
+	}
+	return nil
+}
+
+// checkCounterSchedules returns ErrNoCounterScheduled when the service has counters at the venue
+// and none of them is within its opening hours, in the venue's time zone. Counters without hours are always scheduled.
+func checkCounterSchedules(venue *Venue, serviceID uint, now time.Time) error {
+	now = now.In(venue.Location())
+
+	var counters []Counter
+	query := database.DB.Where("venue_id = ?", venue.VenueID)
+	if err := whereCounterServes(query, serviceID).Find(&counters).Error; err != nil {
+		return err
+	}
+	if len(counters) == 0 {
+		return nil
+	}
+
+	for _, counter := range counters {
+		if _, open := openingPeriodAt(counter.OpenTime, counter.CloseTime, now); open {
+			return nil
+		}
+	}
+	return ErrNoCounterScheduled
+}
+
+// openingPeriodAt reports whether t falls within the daily hours openTime-closeTime and, if so,
+// when that opening period closes. Hours past midnight (e.g. 20:00-02:00) are supported.
+// Missing or malformed hours mean always open, with a nil closing time.
+func openingPeriodAt(openTime string, closeTime string, t time.Time) (*time.Time, bool) {
+	openClock, err := time.Parse(clockLayout, openTime)
+	if err != nil {
+		return nil, true
+	}
+	closeClock, err := time.Parse(clockLayout, closeTime)
+	if err != nil {
+		return nil, true
+	}
+
+	for _, day := range []time.Time{t.AddDate(0, 0, -1), t} {
+		openAt := atClock(day, openClock)
+		closeAt := atClock(day, closeClock)
+		if !closeAt.After(openAt) {
+			closeAt = closeAt.AddDate(0, 0, 1)
+		}
+		if !t.Before(openAt) && t.Before(closeAt) {
+			return &closeAt, true
+		}
+	}
+	return nil, false
+}
+
+// nextClosingTime returns the first time at or after since when the daily closeTime is reached
+func nextClosingTime(closeTime string, since time.Time) (time.Time, bool) {
+	closeClock, err := time.Parse(clockLayout, closeTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	closeAt := atClock(since, closeClock)
+	if closeAt.Before(since) {
+		closeAt = closeAt.AddDate(0, 0, 1)
+	}
+	return closeAt, true
+}
+
+// atClock returns the given day at the clock time of clock
+func atClock(day time.Time, clock time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, day.Location())
+}
@@ -0,0 +1,323 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"queue-system-backend/database"
+	"queue-system-backend/events"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Workflow is a venue's multi-step customer journey, e.g. Registration → Verification → Payment → Pickup.
+// A ticket issued for a workflow moves to the next step's queue when a step is completed.
+type Workflow struct {
+	WorkflowID  uint           `json:"workflow_id" gorm:"primaryKey;autoIncrement"`
+	UserID      uint           `json:"user_id" gorm:"not null;index"`  // Owner of the venue
+	VenueID     uint           `json:"venue_id" gorm:"not null;index"` // Foreign key to Venues table
+	Name        string         `json:"name" gorm:"size:255;not null"`
+	Description string         `json:"description" gorm:"size:255"`
+	Active      bool           `json:"active" gorm:"not null"` // Inactive workflows don't issue tickets
+	CreatedAt   time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	Steps       []WorkflowStep `json:"steps" gorm:"-"`
+}
+
+// TableName ensures GORM uses the correct table name
+func (Workflow) TableName() string {
+	return "Workflows"
+}
+
+// WorkflowStep is one service visited in a workflow
+type WorkflowStep struct {
+	StepID     uint   `json:"step_id" gorm:"primaryKey;autoIncrement"`
+	WorkflowID uint   `json:"workflow_id" gorm:"not null;uniqueIndex:idx_workflow_step_position"`
+	Position   int    `json:"position" gorm:"not null;uniqueIndex:idx_workflow_step_position"` // 1 for the first step
+	ServiceID  uint   `json:"service_id" gorm:"not null"`
+	Name       string `json:"name" gorm:"size:255"` // Shown to the customer; defaults to the service name
+}
+
+// TableName ensures GORM uses the correct table name
+func (WorkflowStep) TableName() string {
+	return "WorkflowSteps"
+}
+
+// validate checks the workflow's steps against its venue and owner and numbers them in order
+func (w *Workflow) validate(tx *gorm.DB) error {
+	if w.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(w.Steps) == 0 {
+		return errors.New("a workflow needs at least one step")
+	}
+
+	for i := range w.Steps {
+		step := &w.Steps[i]
+		var service Service
+		if err := tx.First(&service, step.ServiceID).Error; err != nil {
+			return fmt.Errorf("step %d: service not found", i+1)
+		}
+		if service.UserID == nil || *service.UserID != w.UserID {
+			return fmt.Errorf("step %d: service does not belong to the workflow owner", i+1)
+		}
+		if service.VenueID == nil || *service.VenueID != w.VenueID {
+			return fmt.Errorf("step %d: service does not belong to the workflow's venue", i+1)
+		}
+		if step.Name == "" {
+			step.Name = service.ServiceName
+		}
+		step.StepID = 0
+		step.WorkflowID = w.WorkflowID
+		step.Position = i + 1
+	}
+	return nil
+}
+
+// CreateWorkflow adds a workflow and its steps, in the given order
+func CreateWorkflow(workflow *Workflow) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := workflow.validate(tx); err != nil {
+			return err
+		}
+		if err := tx.Create(workflow).Error; err != nil {
+			return err
+		}
+		for i := range workflow.Steps {
+			workflow.Steps[i].WorkflowID = workflow.WorkflowID
+		}
+		return tx.Create(&workflow.Steps).Error
+	})
+}
+
+// UpdateWorkflow saves a workflow and replaces its steps.
+// Tickets already on the workflow continue from their current position.
+func UpdateWorkflow(workflow *Workflow) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := workflow.validate(tx); err != nil {
+			return err
+		}
+		if err := tx.Save(workflow).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workflow_id = ?", workflow.WorkflowID).Delete(&WorkflowStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&workflow.Steps).Error
+	})
+}
+
+// DeleteWorkflow deletes a workflow and its steps. Tickets on it finish their current step.
+func DeleteWorkflow(workflowID uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workflow_id = ?", workflowID).Delete(&WorkflowStep{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Workflow{}, workflowID).Error
+	})
+}
+
+// GetWorkflowByID retrieves a workflow with its steps
+func GetWorkflowByID(workflowID uint) (*Workflow, error) {
+	var workflow Workflow
+	if err := database.DB.First(&workflow, workflowID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("workflow not found")
+		}
+		return nil, err
+	}
+	if err := loadWorkflowSteps(database.DB, &workflow); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// GetWorkflowsByVenue retrieves a venue's workflows with their steps
+func GetWorkflowsByVenue(venueID uint) ([]Workflow, error) {
+	var workflows []Workflow
+	err := database.DB.Where("venue_id = ?", venueID).Order("workflow_id ASC").Find(&workflows).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range workflows {
+		if err := loadWorkflowSteps(database.DB, &workflows[i]); err != nil {
+			return nil, err
+		}
+	}
+	return workflows, nil
+}
+
+// loadWorkflowSteps fills in a workflow's steps in order
+func loadWorkflowSteps(tx *gorm.DB, workflow *Workflow) error {
+	return tx.Where("workflow_id = ?", workflow.WorkflowID).
+		Order("position ASC").
+		Find(&workflow.Steps).Error
+}
+
+// startWorkflow points a new ticket at the service of its workflow's first step
+func startWorkflow(ticket *QueueTicket) error {
+	workflow, err := GetWorkflowByID(*ticket.WorkflowID)
+	if err != nil {
+		return err
+	}
+	if !workflow.Active {
+		return errors.New("workflow is not active")
+	}
+	if ticket.VenueID != nil && *ticket.VenueID != workflow.VenueID {
+		return errors.New("workflow does not belong to this venue")
+	}
+	if len(workflow.Steps) == 0 {
+		return errors.New("workflow has no steps")
+	}
+
+	ticket.ServiceID = &workflow.Steps[0].ServiceID
+	ticket.WorkflowStep = 1
+	return nil
+}
+
+// workflowAdvance is a ticket that completed a workflow step and joined the next step's queue
+type workflowAdvance struct {
+	completed QueueTicket    // The ticket as it completed the previous step
+	displays  []QueueDisplay // Displays of the next step's queue
+}
+
+// advanceWorkflow moves a ticket that just completed a workflow step into the queue of the next step
+// within tx, recording the completed step as a TicketLeg. It returns nil when the ticket has no workflow or completed its last step.
+func advanceWorkflow(tx *gorm.DB, ticket *QueueTicket, actor TicketActor) (*workflowAdvance, error) {
+	if ticket.WorkflowID == nil || ticket.Status != TicketStatusCompleted {
+		return nil, nil
+	}
+
+	var next WorkflowStep
+	err := tx.Where("workflow_id = ? AND position > ?", *ticket.WorkflowID, ticket.WorkflowStep).
+		Order("position ASC").
+		Take(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	advance := &workflowAdvance{completed: *ticket}
+	now := time.Now()
+	if err := endTicketLeg(tx, ticket, LegEndedByWorkflow, now); err != nil {
+		return nil, err
+	}
+	updates := ticket.startTicketLeg(map[string]interface{}{
+		"status":              TicketStatusWaiting,
+		"service_id":          next.ServiceID,
+		"assigned_counter_id": nil,
+		"at_head":             false,
+		"queued_at":           now,
+		"workflow_step":       next.Position,
+	}, now)
+	if err := tx.Model(&QueueTicket{}).Where("ticket_id = ?", ticket.TicketID).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	ticket.Status = TicketStatusWaiting
+	ticket.ServiceID = &next.ServiceID
+	ticket.AssignedCounterID = nil
+	ticket.AtHead = false
+	ticket.QueuedAt = &now
+	ticket.WorkflowStep = next.Position
+
+	event := QueueTicketEvent{
+		TicketID:   ticket.TicketID,
+		FromStatus: TicketStatusCompleted,
+		ToStatus:   TicketStatusWaiting,
+		OperatorID: actor.OperatorID,
+		CounterID:  actor.CounterID,
+		Reason:     fmt.Sprintf("workflow step %d: %s", next.Position, next.Name),
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return nil, errors.New("failed to record ticket event: " + err.Error())
+	}
+
+	if ticket.VenueID != nil {
+		advance.displays, err = refreshNextTickets(tx, *ticket.VenueID, next.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return advance, nil
+}
+
+// publishTicketCompletion publishes a completed ticket, or the completed step and the
+// next one when the ticket moved on in its workflow
+func publishTicketCompletion(ticket *QueueTicket, advance *workflowAdvance) {
+	if advance == nil {
+		publishTicketEvent(ticket)
+		return
+	}
+	publishTicketEvent(&advance.completed)
+	publishTicket(events.TypeTicketAdvanced, ticket)
+	publishDisplayEvents(advance.displays)
+}
+
+// JourneyStep is a workflow step as shown to the customer
+type JourneyStep struct {
+	Position    int    `json:"position"`
+	Name        string `json:"name"`
+	ServiceID   uint   `json:"service_id"`
+	ServiceName string `json:"service_name"`
+	Status      string `json:"status"` // completed, current or pending
+}
+
+// TicketJourney is a ticket's progress through its workflow
+type TicketJourney struct {
+	WorkflowID     uint          `json:"workflow_id"`
+	Name           string        `json:"name"`
+	CurrentStep    int           `json:"current_step"`
+	TotalSteps     int           `json:"total_steps"`
+	RemainingSteps int           `json:"remaining_steps"` // Steps after the current one
+	Steps          []JourneyStep `json:"steps"`
+}
+
+// GetTicketJourney returns a ticket's progress through its workflow, or nil for single-service
+// tickets and tickets whose workflow was deleted
+func GetTicketJourney(ticket *QueueTicket) (*TicketJourney, error) {
+	if ticket.WorkflowID == nil {
+		return nil, nil
+	}
+	workflow, err := GetWorkflowByID(*ticket.WorkflowID)
+	if err != nil {
+		if err.Error() == "workflow not found" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	journey := &TicketJourney{
+		WorkflowID:  workflow.WorkflowID,
+		Name:        workflow.Name,
+		CurrentStep: ticket.WorkflowStep,
+		TotalSteps:  len(workflow.Steps),
+		Steps:       make([]JourneyStep, 0, len(workflow.Steps)),
+	}
+	for _, step := range workflow.Steps {
+		serviceName, err := GetServiceNameByID(step.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+
+		status := "pending"
+		switch {
+		case step.Position < ticket.WorkflowStep:
+			status = "completed"
+		case step.Position == ticket.WorkflowStep && ticket.Status == TicketStatusCompleted:
+			status = "completed"
+		case step.Position == ticket.WorkflowStep:
+			status = "current"
+		default:
+			journey.RemainingSteps++
+		}
+		journey.Steps = append(journey.Steps, JourneyStep{
+			Position:    step.Position,
+			Name:        step.Name,
+			ServiceID:   step.ServiceID,
+			ServiceName: serviceName,
+			Status:      status,
+		})
+	}
+	return journey, nil
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"queue-system-backend/controllers"
+	"queue-system-backend/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAppointmentRoutes registers the public booking routes and the staff appointment routes
+func RegisterAppointmentRoutes(router *gin.Engine) {
+	// Public endpoints customers book and manage their appointment with
+	booking := router.Group("/booking")
+	{
+		booking.GET("/slots", controllers.ListAppointmentSlots)
+		booking.POST("", controllers.BookAppointmentHandler)
+		booking.GET("/:code", controllers.GetAppointmentByCodeHandler)
+		booking.POST("/:code/cancel", controllers.CancelAppointmentByCodeHandler)
+		booking.POST("/:code/reschedule", controllers.RescheduleAppointmentHandler)
+	}
+
+	appointments := router.Group("/appointments").Use(middlewares.AuthMiddleware())
+	{
+		appointments.POST("/check-in", controllers.CheckInAppointmentHandler)
+		appointments.POST("/:id/cancel", controllers.CancelAppointmentHandler)
+		appointments.POST("/:id/no-show", controllers.MarkAppointmentNoShowHandler)
+	}
+}
@@ -15,6 +15,9 @@ func RegisterCounterRoutes(router *gin.Engine) {
 		counters.GET("/", controllers.GetCounters)
 		counters.PUT("/:id", controllers.UpdateCounter)
 		counters.DELETE("/:id", controllers.DeleteCounter)
+		counters.GET("/:id/services", controllers.GetCounterServices)
+		counters.PUT("/:id/services", controllers.SetCounterServices)
+		counters.GET("/:id/sessions", controllers.GetCounterSessionLog)
 		//counters.GET("/company/:company_id", controllers.GetCountersByCompany)
 		counters.GET("/venue/:venue_id", controllers.GetCountersByVenue)
 	}
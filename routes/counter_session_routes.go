@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"queue-system-backend/controllers"
+	"queue-system-backend/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCounterSessionRoutes registers the routes operators use to open, pause, resume and close their counter
+func RegisterCounterSessionRoutes(router *gin.Engine) {
+	sessions := router.Group("/counter-session").Use(middlewares.AuthMiddleware())
+	{
+		sessions.GET("/", controllers.GetCurrentCounterSession)
+		sessions.POST("/open", controllers.OpenCounterSession)
+		sessions.POST("/pause", controllers.PauseCounterSession)
+		sessions.POST("/resume", controllers.ResumeCounterSession)
+		sessions.POST("/close", controllers.CloseCounterSession)
+	}
+}
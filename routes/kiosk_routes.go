@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"queue-system-backend/controllers"
+	"queue-system-backend/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterKioskRoutes(router *gin.Engine) {
+	// Device registration, managed by the venue owner
+	kiosks := router.Group("/kiosks").Use(middlewares.AuthMiddleware(), middlewares.AdminMiddleware())
+	{
+		kiosks.GET("", controllers.ListKioskDevices)
+		kiosks.POST("", controllers.CreateKioskDevice)
+		kiosks.GET("/:id", controllers.GetKioskDevice)
+		kiosks.PUT("/:id", controllers.UpdateKioskDevice)
+		kiosks.DELETE("/:id", controllers.DeleteKioskDevice)
+		kiosks.POST("/:id/pairing-code", controllers.ResetKioskPairing)
+	}
+
+	// Endpoints called by the kiosk devices themselves
+	router.POST("/kiosk/pair", controllers.PairKioskDevice)
+	kiosk := router.Group("/kiosk").Use(middlewares.KioskMiddleware())
+	{
+		kiosk.GET("/services", controllers.ListKioskServices)
+		kiosk.POST("/tickets", controllers.CreateKioskTicketHandler)
+		kiosk.POST("/check-in", controllers.KioskCheckInHandler)
+	}
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"queue-system-backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterOperatorConsoleRoutes(router *gin.Engine) {
+	// Authenticated inside the handler, browsers cannot send an Authorization header on WebSocket requests
+	router.GET("/ws/operator", controllers.OperatorConsole)
+}
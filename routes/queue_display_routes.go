@@ -8,6 +8,10 @@ import (
 )
 
 func RegisterQueueDisplayRoutes(router *gin.Engine) {
+	// Public so display screens can connect with EventSource, which cannot send an Authorization header.
+	// It requires a venue_id and only carries the public parts of queue events.
+	router.GET("/display/stream", controllers.StreamQueueDisplay)
+
 	displayRoutes := router.Group("/display").Use(middlewares.AuthMiddleware())
 
 	{
@@ -15,6 +19,7 @@ func RegisterQueueDisplayRoutes(router *gin.Engine) {
 		displayRoutes.POST("/create", controllers.CreateQueueDisplay)
 		displayRoutes.PUT("/update", controllers.UpdateQueueDisplay)
 		displayRoutes.PUT("/:counter_id/next", controllers.AssignNextTicket)
+		displayRoutes.PUT("/:counter_id/next-batch", controllers.AssignNextTickets)
 		displayRoutes.GET("/all", controllers.GetAllQueueDisplays)
 		displayRoutes.GET("/next-counter", controllers.GetNextCounterController)
 		displayRoutes.POST("/reset", controllers.ResetQueueDisplayHandler)
@@ -15,5 +15,9 @@ func QueueTicketRoutes(router *gin.Engine) {
 		tickets.PUT("/:id", controllers.UpdateQueueTicketHandler)
 		tickets.DELETE("/:id", controllers.DeleteQueueTicketHandler)
 		tickets.PUT("/:id/status", controllers.UpdateQueueTicketStatusHandler)
+		tickets.GET("/:id/history", controllers.GetQueueTicketHistoryHandler)
+		tickets.POST("/:id/transfer", controllers.TransferQueueTicketHandler)
+		tickets.POST("/:id/recall", controllers.RecallQueueTicketHandler)
+		tickets.POST("/:id/rejoin", controllers.RejoinQueueTicketHandler)
 	}
 }
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"queue-system-backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRemoteJoinRoutes registers the public routes customers join a venue's queues remotely with
+func RegisterRemoteJoinRoutes(router *gin.Engine) {
+	router.GET("/join/:venue_id", controllers.ListRemoteJoinServices)
+	router.POST("/join/:venue_id", controllers.JoinQueueRemotelyHandler)
+}
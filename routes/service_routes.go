@@ -16,5 +16,9 @@ func ServiceRoutes(router *gin.Engine) {
 		services.POST("", middlewares.AdminMiddleware(), controllers.CreateService)
 		services.PUT("/:id", middlewares.AdminMiddleware(), controllers.UpdateService)
 		services.DELETE("/:id", middlewares.AdminMiddleware(), controllers.DeleteService)
+		services.GET("/:id/slot-templates", controllers.ListSlotTemplates)
+		services.POST("/:id/slot-templates", middlewares.AdminMiddleware(), controllers.CreateSlotTemplate)
+		services.PUT("/:id/slot-templates/:template_id", middlewares.AdminMiddleware(), controllers.UpdateSlotTemplate)
+		services.DELETE("/:id/slot-templates/:template_id", middlewares.AdminMiddleware(), controllers.DeleteSlotTemplate)
 	}
 }
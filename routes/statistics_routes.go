@@ -14,5 +14,13 @@ func SetupStatisticsRoutes(r *gin.Engine) {
 		statistics.POST("/active-queues", statsController.GetActiveQueues)
 		statistics.POST("/average-wait-time", statsController.GetAverageWaitTime)
 		statistics.POST("/total-served", statsController.GetTotalServed)
+		statistics.POST("/customer-cancelled", statsController.GetCustomerCancelled)
+		statistics.POST("/counter-utilization", statsController.GetCounterUtilization)
+		statistics.POST("/transfers", statsController.GetTransfers)
+		statistics.POST("/series", statsController.GetStatisticsSeries)
+		statistics.POST("/service-performance", statsController.GetServicePerformance)
+		statistics.POST("/operator-performance", statsController.GetOperatorPerformance)
+		statistics.POST("/arrival-heatmap", statsController.GetArrivalHeatmap)
+		statistics.POST("/staffing", statsController.GetStaffingRecommendation)
 	}
 }
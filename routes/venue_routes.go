@@ -15,5 +15,16 @@ func VenueRoutes(router *gin.Engine) {
 		venues.GET("/:id", controllers.GetVenue)
 		venues.PUT("/:id", middlewares.AdminMiddleware(), controllers.UpdateVenue)
 		venues.DELETE("/:id", middlewares.AdminMiddleware(), controllers.DeleteVenue)
+		venues.GET("/:id/closures", controllers.ListVenueClosures)
+		venues.POST("/:id/closures", middlewares.AdminMiddleware(), controllers.CreateVenueClosure)
+		venues.PUT("/:id/closures/:closure_id", middlewares.AdminMiddleware(), controllers.UpdateVenueClosure)
+		venues.DELETE("/:id/closures/:closure_id", middlewares.AdminMiddleware(), controllers.DeleteVenueClosure)
+		venues.GET("/:id/workflows", controllers.ListWorkflows)
+		venues.GET("/:id/workflows/:workflow_id", controllers.GetWorkflow)
+		venues.POST("/:id/workflows", middlewares.AdminMiddleware(), controllers.CreateWorkflow)
+		venues.PUT("/:id/workflows/:workflow_id", middlewares.AdminMiddleware(), controllers.UpdateWorkflow)
+		venues.DELETE("/:id/workflows/:workflow_id", middlewares.AdminMiddleware(), controllers.DeleteWorkflow)
+		venues.GET("/:id/appointments", controllers.ListVenueAppointments)
+		venues.POST("/:id/simulation", controllers.SimulateVenueQueue)
 	}
 }
@@ -8,5 +8,8 @@ import (
 
 func RegisterViewTicketRoutes(router *gin.Engine) {
 	router.GET("/myticket/:token", controllers.GetQueueTicketByTokenHandler)
+	router.POST("/myticket/:token/cancel", controllers.CancelQueueTicketByTokenHandler)
+	router.POST("/myticket/:token/postpone", controllers.PostponeQueueTicketByTokenHandler)
+	router.POST("/myticket/:token/rejoin", controllers.RejoinQueueTicketByTokenHandler)
 	router.GET("/waiting-tickets", controllers.GetWaitingQueueTicketsHandler) // New route
 }
@@ -0,0 +1,34 @@
+package utils
+
+import "strings"
+
+// allowedOrigins are the browser origins allowed to call the API, from the comma separated
+// ALLOWED_ORIGINS, or the frontends used in development
+var allowedOrigins = parseOrigins(getEnv("ALLOWED_ORIGINS", "http://localhost:5173,http://localhost:8081,http://localhost,https://reqbin.com/"))
+
+// parseOrigins splits a comma separated list of origins
+func parseOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// AllowedOrigins returns the browser origins allowed to call the API
+func AllowedOrigins() []string {
+	return allowedOrigins
+}
+
+// IsAllowedOrigin reports whether a request's Origin header is one of the allowed origins
+func IsAllowedOrigin(origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, allowed := range allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
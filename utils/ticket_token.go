@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	// ticketTokenBytes is the entropy of a ticket token (128 bits)
+	ticketTokenBytes = 16
+	// ticketSignatureBytes is the length the HMAC is truncated to
+	ticketSignatureBytes = 16
+	// legacyTicketTokenLength is the length of the base62 tokens issued before random tokens
+	legacyTicketTokenLength = 8
+)
+
+// ticketTokenSecret enables signed ticket tokens when set.
+// Changing it invalidates the signed tokens already handed out.
+var ticketTokenSecret = []byte(getEnv("TICKET_TOKEN_SECRET", ""))
+
+// acceptLegacyTicketTokens keeps unsigned tokens issued before signing was enabled valid while
+// signing is on. Turn it off once those tickets are gone so forged tokens never reach the database.
+var acceptLegacyTicketTokens = getEnv("TICKET_TOKEN_ACCEPT_LEGACY", "false") == "true"
+
+var tokenEncoding = base64.RawURLEncoding
+
+// GenerateTicketToken returns a random URL-safe token for a customer ticket.
+// With TICKET_TOKEN_SECRET set the token carries an HMAC, e.g. "<random>.<signature>".
+func GenerateTicketToken() (string, error) {
+	buf := make([]byte, ticketTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	token := tokenEncoding.EncodeToString(buf)
+	if len(ticketTokenSecret) == 0 {
+		return token, nil
+	}
+	return token + "." + signTicketToken(token), nil
+}
+
+// VerifyTicketToken reports whether a token can belong to a ticket, without a database lookup.
+// With signing enabled a token's signature must match; otherwise only the format is checked.
+// With TICKET_TOKEN_ACCEPT_LEGACY=true, unsigned tokens in the exact shape of the legacy
+// base62 tokens or of random tokens issued before signing was enabled are accepted too.
+func VerifyTicketToken(token string) bool {
+	if token == "" || len(token) > 255 {
+		return false
+	}
+	if len(ticketTokenSecret) == 0 {
+		return true
+	}
+
+	random, signature, found := strings.Cut(token, ".")
+	if !found {
+		return acceptLegacyTicketTokens && isLegacyTicketToken(token)
+	}
+	if len(random) != tokenEncoding.EncodedLen(ticketTokenBytes) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(signTicketToken(random)))
+}
+
+// isLegacyTicketToken reports whether an unsigned token has the shape of a token issued
+// without signing: 8 base62 characters, or a random token without its signature
+func isLegacyTicketToken(token string) bool {
+	switch len(token) {
+	case legacyTicketTokenLength:
+		for _, r := range token {
+			if !(r >= '0' && r <= '9') && !(r >= 'A' && r <= 'Z') && !(r >= 'a' && r <= 'z') {
+				return false
+			}
+		}
+		return true
+	case tokenEncoding.EncodedLen(ticketTokenBytes):
+		decoded, err := tokenEncoding.DecodeString(token)
+		return err == nil && len(decoded) == ticketTokenBytes
+	default:
+		return false
+	}
+}
+
+// signTicketToken returns the truncated HMAC-SHA256 of the random part of a token
+func signTicketToken(random string) string {
+	mac := hmac.New(sha256.New, ticketTokenSecret)
+	mac.Write([]byte(random))
+	return tokenEncoding.EncodeToString(mac.Sum(nil)[:ticketSignatureBytes])
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyTicketToken(t *testing.T) {
+	defer func(secret []byte, legacy bool) {
+		ticketTokenSecret, acceptLegacyTicketTokens = secret, legacy
+	}(ticketTokenSecret, acceptLegacyTicketTokens)
+
+	ticketTokenSecret = []byte("test-secret")
+	signed, err := GenerateTicketToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	random, signature, _ := strings.Cut(signed, ".")
+	tampered := random + "." + strings.Repeat("A", len(signature))
+
+	ticketTokenSecret = nil
+	unsigned, err := GenerateTicketToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		legacy bool
+		token  string
+		want   bool
+	}{
+		{"empty token", "", false, "", false},
+		{"too long", "", false, strings.Repeat("a", 256), false},
+		{"unsigned without signing", "", false, unsigned, true},
+		{"any format without signing", "", false, "not-a-real-token", true},
+		{"signed token", "test-secret", false, signed, true},
+		{"signed with another secret", "other-secret", false, signed, false},
+		{"tampered signature", "test-secret", false, tampered, false},
+		{"wrong random length", "test-secret", false, "abc." + signature, false},
+		{"unsigned random rejected", "test-secret", false, unsigned, false},
+		{"legacy base62 rejected", "test-secret", false, "Ab3dE9xZ", false},
+		{"unsigned random accepted as legacy", "test-secret", true, unsigned, true},
+		{"legacy base62 accepted", "test-secret", true, "Ab3dE9xZ", true},
+		{"legacy with other characters", "test-secret", true, "Ab3d-9xZ", false},
+		{"legacy with another length", "test-secret", true, "Ab3dE9x", false},
+		{"signed token with legacy on", "test-secret", true, signed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticketTokenSecret = []byte(tt.secret)
+			acceptLegacyTicketTokens = tt.legacy
+			if got := VerifyTicketToken(tt.token); got != tt.want {
+				t.Errorf("VerifyTicketToken(%q) = %v, want %v", tt.token, got, tt.want)
+			}
+		})
+	}
+}